@@ -1,9 +1,6 @@
 package tgbot
 
-import (
-	"fmt"
-	"regexp"
-)
+import "regexp"
 
 // ConditionCallStructure ...
 type ConditionCallStructure interface {
@@ -57,6 +54,13 @@ func (cc *ChainStructure) AddToConditionalFuncs(cf ConditionCallStructure) {
 	cc.chainf = append(cc.chainf, cf)
 }
 
+// wrapLast replaces the last function of the chain with the wrapped one.
+func (cc *ChainStructure) wrapLast(wrap func(ConditionCallStructure) ConditionCallStructure) {
+	if len(cc.chainf) > 0 {
+		cc.chainf[len(cc.chainf)-1] = wrap(cc.chainf[len(cc.chainf)-1])
+	}
+}
+
 // SetLoop ...
 func (cc *ChainStructure) SetLoop(b bool) {
 	cc.loop = b
@@ -480,6 +484,23 @@ func (tcc TextConditionalCall) call(bot TgBot, msg Message) {
 	}
 }
 
+// CommandConditionalCall is a TextConditionalCall for commands, it ignores the commands sent to other bots (/command@otherbot).
+type CommandConditionalCall struct {
+	TextConditionalCall
+}
+
+// canCall ...
+func (ccc CommandConditionalCall) canCall(bot TgBot, msg Message) bool {
+	return bot.commandForMe(msg) && ccc.TextConditionalCall.canCall(bot, msg)
+}
+
+// call ...
+func (ccc CommandConditionalCall) call(bot TgBot, msg Message) {
+	if bot.commandForMe(msg) {
+		ccc.TextConditionalCall.call(bot, msg)
+	}
+}
+
 // CommandStructure ...
 type CommandStructure interface {
 	canCall(string) bool
@@ -538,7 +559,7 @@ func (rc MultiRegexCommand) canCall(text string) bool {
 func (rc MultiRegexCommand) call(bot TgBot, msg Message, text string) {
 	canC, regexToUse := rc.getRegexMatch(text)
 	if !canC {
-		fmt.Println("Error")
+		bot.log().Error("No regular expression matches the message", "chat_id", msg.Chat.ID, "message_id", msg.ID)
 		return
 	}
 	vals := regexToUse.FindStringSubmatch(text)
@@ -54,7 +54,6 @@ func buildHelpMessage(complete bool) string {
 func hideKeyboard(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
 	rkm := tgbot.ReplyKeyboardHide{HideKeyboard: true, Selective: false}
 	bot.Answer(msg).Text("Hidden it!").KeyboardHide(rkm).End()
-	// bot.SendMessageWithKeyboardHide(msg.Chat.ID, "Hiden it!", nil, nil, rkm)
 	return nil
 }
 
@@ -66,7 +65,6 @@ func cmdKeyboard(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
 		OneTimeKeyboard: false,
 		Selective:       false}
 	bot.Answer(msg).Text("Enjoy the keyboard").Keyboard(rkm).End()
-	// bot.SendMessageWithKeyboard(msg.Chat.ID, "Enjoy the keyboard", nil, nil, rkm)
 	return nil
 }
 
@@ -77,7 +75,6 @@ func hardEcho(bot tgbot.TgBot, msg tgbot.Message, vals []string, kvals map[strin
 	}
 	rkm := tgbot.ForceReply{Force: true, Selective: false}
 	bot.Answer(msg).Text(msgtext).ForceReply(rkm).End()
-	// bot.SendMessageWithForceReply(msg.Chat.ID, msgtext, nil, nil, rkm)
 	return nil
 }
 
@@ -143,7 +140,6 @@ func showMeHand(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
 		OneTimeKeyboard: true,
 		Selective:       false}
 	bot.Answer(msg).Text("There you have the commands! http://google.com").Keyboard(rkm).End()
-	// bot.SendMessageWithKeyboard(msg.Chat.ID, "There you have the commands!", nil, nil, rkm)
 	return nil
 }
 
@@ -172,7 +168,7 @@ func imageResend(bot tgbot.TgBot, msg tgbot.Message, photos []tgbot.PhotoSize, i
 }
 
 func sendImage(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
-	// bot.SendPhotoQuery(tgbot.SendPhotoPathQuery{msg.Chat.ID, "test.jpg", nil, nil, nil})
+	// bot.SendPhotoQuery(tgbot.SendPhotoPathQuery{msg.Chat.ID, "test.jpg", nil, tgbot.SendOptions{}})
 	// bot.SendPhoto(msg.Chat.ID, "test.jpg", nil, nil, nil)
 	// bot.SimpleSendPhoto(msg, "example/simpleexample/files/test.jpg")
 	bot.Answer(msg).Photo("example/simpleexample/files/test.jpg").End()
@@ -190,7 +186,6 @@ func sendImageWithKey(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
 		Photo("example/simpleexample/files/test.jpg").
 		Keyboard(rkm).
 		End()
-	// bot.SendPhotoWithKeyboard(msg.Chat.ID, "example/simpleexample/files/test.jpg", nil, nil, rkm)
 	return nil
 }
 
@@ -321,7 +316,6 @@ func instPic(bot tgbot.TgBot, msg tgbot.Message, text string) *string {
 		Caption(caption).
 		Keyboard(rkm).
 		End()
-	// bot.SendPhotoWithKeyboard(msg.Chat.ID, img, &caption, nil, rkm)
 	return nil
 }
 
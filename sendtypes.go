@@ -14,48 +14,62 @@ type Send struct {
 
 // Text return a SendText instance to chain actions easy
 func (s *Send) Text(text string) *SendText {
-	return &SendText{s, text, nil, nil, nil, nil}
+	sp := &SendText{Text: text}
+	return sp.init(s, sp)
+}
+
+// Formatted return a SendText instance with the formatted text, see NewMarkdownV2Builder, NewHTMLBuilder and NewEntitiesBuilder
+func (s *Send) Formatted(ft FormattedText) *SendText {
+	sp := &SendText{Text: ft.Text(), ParseModeS: ft.ParseMode(), Entities: ft.Entities()}
+	return sp.init(s, sp)
 }
 
 // Forward return a SendForward instance to chain actions easy
 func (s *Send) Forward(to int, msg int) *SendForward {
-	return &SendForward{s, to, msg}
+	sp := &SendForward{to: to, msg: msg}
+	return sp.init(s, sp)
 }
 
 // Photo return a SendPhoto instance to chain actions easy
 func (s *Send) Photo(photo interface{}) *SendPhoto {
-	// Check here that the interface works?
-	return &SendPhoto{s, photo, nil, nil, nil}
+	sp := &SendPhoto{Photo: photo}
+	return sp.init(s, sp)
 }
 
 // Audio return a SendAudio instance to chain actions easy
 func (s *Send) Audio(audio string) *SendAudio {
-	return &SendAudio{s, audio, nil, nil, nil, nil, nil}
+	sp := &SendAudio{Audio: audio}
+	return sp.init(s, sp)
 }
 
 // Voice return a SendVoice instance to chain actions easy
 func (s *Send) Voice(voice string) *SendVoice {
-	return &SendVoice{s, voice, nil, nil, nil}
+	sp := &SendVoice{Voice: voice}
+	return sp.init(s, sp)
 }
 
 // Document return a SendDocument instance to chain actions easy
 func (s *Send) Document(doc interface{}) *SendDocument {
-	return &SendDocument{s, doc, nil, nil}
+	sp := &SendDocument{Document: doc}
+	return sp.init(s, sp)
 }
 
 // Sticker return a SendSticker instance to chain actions easy
 func (s *Send) Sticker(stick interface{}) *SendSticker {
-	return &SendSticker{s, stick, nil, nil}
+	sp := &SendSticker{Sticker: stick}
+	return sp.init(s, sp)
 }
 
 // Video return a SendVideo instance to chain actions easy
 func (s *Send) Video(vid string) *SendVideo {
-	return &SendVideo{s, vid, nil, nil, nil, nil}
+	sp := &SendVideo{Video: vid}
+	return sp.init(s, sp)
 }
 
 // Location return a SendLocation instance to chain actions easy
 func (s *Send) Location(latitude float64, long float64) *SendLocation {
-	return &SendLocation{s, latitude, long, nil, nil}
+	sp := &SendLocation{Latitude: latitude, Longitude: long}
+	return sp.init(s, sp)
 }
 
 // Action return a SendAction instance to chain actions easy
@@ -63,14 +77,78 @@ func (s *Send) Action(action ChatAction) *SendChatAction {
 	return &SendChatAction{s, action}
 }
 
+// SendCommon is the core of all the Send builders, it keeps the options that every message has.
+// Its methods return the builder B that embeds it, so they can be chained with the methods of the builder.
+type SendCommon[B any] struct {
+	Send    *Send
+	Options SendOptions
+	builder *B
+}
+
+// init links the core with its builder.
+func (sc *SendCommon[B]) init(s *Send, b *B) *B {
+	sc.Send, sc.builder = s, b
+	return b
+}
+
+// ReplyToMessage ...
+func (sc *SendCommon[B]) ReplyToMessage(rm int) *B {
+	sc.Options.ReplyToMessageID = &rm
+	return sc.builder
+}
+
+// Markup sets any reply markup.
+func (sc *SendCommon[B]) Markup(rm ReplyMarkupInt) *B {
+	sc.Options.ReplyMarkup = &rm
+	return sc.builder
+}
+
+// Keyboard ...
+func (sc *SendCommon[B]) Keyboard(kb ReplyKeyboardMarkup) *B {
+	return sc.Markup(kb)
+}
+
+// KeyboardHide ...
+func (sc *SendCommon[B]) KeyboardHide(kb ReplyKeyboardHide) *B {
+	return sc.Markup(kb)
+}
+
+// ForceReply ...
+func (sc *SendCommon[B]) ForceReply(fr ForceReply) *B {
+	return sc.Markup(fr)
+}
+
+// DisableNotification sends the message silently.
+func (sc *SendCommon[B]) DisableNotification(disable bool) *B {
+	sc.Options.DisableNotification = &disable
+	return sc.builder
+}
+
+// ProtectContent protects the message from being forwarded and saved.
+func (sc *SendCommon[B]) ProtectContent(protect bool) *B {
+	sc.Options.ProtectContent = &protect
+	return sc.builder
+}
+
+// AllowSendingWithoutReply sends the message even if the message it replies to was deleted.
+func (sc *SendCommon[B]) AllowSendingWithoutReply(allow bool) *B {
+	sc.Options.AllowSendingWithoutReply = &allow
+	return sc.builder
+}
+
+// Thread sends the message to the thread (topic) of the chat.
+func (sc *SendCommon[B]) Thread(id int) *B {
+	sc.Options.MessageThreadID = &id
+	return sc.builder
+}
+
 // SendText ...
 type SendText struct {
-	Send                  *Send
+	SendCommon[SendText]
 	Text                  string
 	ParseModeS            *ParseModeT
 	DisableWebPagePreview *bool
-	ReplyToMessageID      *int
-	ReplyMarkup           *ReplyMarkupInt
+	Entities              *[]MessageEntity
 }
 
 func (sp *SendText) ParseMode(pm ParseModeT) *SendText {
@@ -84,57 +162,38 @@ func (sp *SendText) DisablePreview(disab bool) *SendText {
 	return sp
 }
 
-// ReplyToMessage ...
-func (sp *SendText) ReplyToMessage(rm int) *SendText {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendText) Keyboard(kb ReplyKeyboardMarkup) *SendText {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendText) KeyboardHide(kb ReplyKeyboardHide) *SendText {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
+// End ...
+func (sp SendText) End() ResultWithMessage {
+	return sp.Send.Bot.sendMessage(sp.Send.ChatID, sp.formatted(), sp.DisableWebPagePreview, sp.Options)
 }
 
-// ForceReply ...
-func (sp *SendText) ForceReply(fr ForceReply) *SendText {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
+// EndSplit sends the text split in as many messages as needed, see SendLongMessage.
+func (sp SendText) EndSplit() ([]Message, error) {
+	return sp.Send.Bot.sendLongMessage(sp.Send.ChatID, sp.formatted(), sp.DisableWebPagePreview, sp.Options)
 }
 
-// End ...
-func (sp SendText) End() ResultWithMessage {
-	return sp.Send.Bot.SendMessage(sp.Send.ChatID, sp.Text, sp.ParseModeS, sp.DisableWebPagePreview, sp.ReplyToMessageID, sp.ReplyMarkup)
+func (sp SendText) formatted() FormattedText {
+	return formattedText{sp.Text, sp.ParseModeS, sp.Entities}
 }
 
 // SendForward ...
 type SendForward struct {
-	Send *Send
-	to   int
-	msg  int
+	SendCommon[SendForward]
+	to  int
+	msg int
 }
 
 // End ...
 func (sf *SendForward) End() ResultWithMessage {
-	return sf.Send.Bot.ForwardMessage(sf.Send.ChatID, sf.to, sf.msg)
+	return sf.Send.Bot.ForwardMessageQuery(ForwardMessageQuery{sf.Send.ChatID, sf.to, sf.msg, sf.Options})
 }
 
 // SendPhoto ...
 type SendPhoto struct {
-	Send             *Send
-	Photo            interface{}
-	CaptionField     *string
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
+	SendCommon[SendPhoto]
+	Photo        interface{}
+	CaptionField *string
+	ParseModeS   *ParseModeT
 }
 
 // Caption ...
@@ -143,47 +202,38 @@ func (sp *SendPhoto) Caption(caption string) *SendPhoto {
 	return sp
 }
 
-// ReplyToMessage ...
-func (sp *SendPhoto) ReplyToMessage(rm int) *SendPhoto {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendPhoto) Keyboard(kb ReplyKeyboardMarkup) *SendPhoto {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendPhoto) KeyboardHide(kb ReplyKeyboardHide) *SendPhoto {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
+// ParseMode sets the parse mode of the caption.
+func (sp *SendPhoto) ParseMode(pm ParseModeT) *SendPhoto {
+	sp.ParseModeS = &pm
 	return sp
 }
 
-// ForceReply ...
-func (sp *SendPhoto) ForceReply(fr ForceReply) *SendPhoto {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
+func (sp SendPhoto) caption() FormattedText {
+	if sp.CaptionField == nil {
+		return nil
+	}
+	return formattedText{*sp.CaptionField, sp.ParseModeS, nil}
 }
 
 // End ...
 func (sp SendPhoto) End() ResultWithMessage {
-	return sp.Send.Bot.SendPhoto(sp.Send.ChatID, sp.Photo, sp.CaptionField, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.sendPhoto(sp.Send.ChatID, sp.Photo, sp.caption(), sp.Options)
+}
+
+// EndSplit sends the media, if the caption is too long the rest is sent in messages after it, with the keyboard in the last one.
+func (sp SendPhoto) EndSplit() ([]Message, error) {
+	return sp.Send.Bot.sendWithLongCaption(sp.Send.ChatID, sp.caption(), sp.Options, func(caption FormattedText, opts SendOptions) ResultWithMessage {
+		return sp.Send.Bot.sendPhoto(sp.Send.ChatID, sp.Photo, caption, opts)
+	})
 }
 
 // SendAudio ...
 type SendAudio struct {
-	Send             *Send
-	Audio            string
-	DurationField    *int
-	PerformerField   *string
-	TitleField       *string
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
+	SendCommon[SendAudio]
+	Audio          string
+	DurationField  *int
+	PerformerField *string
+	TitleField     *string
 }
 
 // Duration ...
@@ -204,51 +254,21 @@ func (sp *SendAudio) Title(d string) *SendAudio {
 	return sp
 }
 
-// ReplyToMessage ...
-func (sp *SendAudio) ReplyToMessage(rm int) *SendAudio {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendAudio) Keyboard(kb ReplyKeyboardMarkup) *SendAudio {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendAudio) KeyboardHide(kb ReplyKeyboardHide) *SendAudio {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// ForceReply ...
-func (sp *SendAudio) ForceReply(fr ForceReply) *SendAudio {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
 // End ...
 func (sp SendAudio) End() ResultWithMessage {
-	return sp.Send.Bot.SendAudio(sp.Send.ChatID,
+	return sp.Send.Bot.sendAudio(sp.Send.ChatID,
 		sp.Audio,
 		sp.DurationField,
 		sp.PerformerField,
 		sp.TitleField,
-		sp.ReplyToMessageID,
-		sp.ReplyMarkup)
+		sp.Options)
 }
 
 // SendVoice ...
 type SendVoice struct {
-	Send             *Send
-	Voice            string
-	DurationField    *int
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
+	SendCommon[SendVoice]
+	Voice         string
+	DurationField *int
 }
 
 // Duration ...
@@ -257,132 +277,40 @@ func (sp *SendVoice) Duration(d int) *SendVoice {
 	return sp
 }
 
-// ReplyToMessage ...
-func (sp *SendVoice) ReplyToMessage(rm int) *SendVoice {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendVoice) Keyboard(kb ReplyKeyboardMarkup) *SendVoice {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendVoice) KeyboardHide(kb ReplyKeyboardHide) *SendVoice {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// ForceReply ...
-func (sp *SendVoice) ForceReply(fr ForceReply) *SendVoice {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
 // End ...
 func (sp SendVoice) End() ResultWithMessage {
-	return sp.Send.Bot.SendVoice(sp.Send.ChatID, sp.Voice, sp.DurationField, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.sendVoice(sp.Send.ChatID, sp.Voice, sp.DurationField, sp.Options)
 }
 
 // SendDocument ...
 type SendDocument struct {
-	Send             *Send
-	Document         interface{}
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
-}
-
-// ReplyToMessage ...
-func (sp *SendDocument) ReplyToMessage(rm int) *SendDocument {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendDocument) Keyboard(kb ReplyKeyboardMarkup) *SendDocument {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendDocument) KeyboardHide(kb ReplyKeyboardHide) *SendDocument {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// ForceReply ...
-func (sp *SendDocument) ForceReply(fr ForceReply) *SendDocument {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
+	SendCommon[SendDocument]
+	Document interface{}
 }
 
 // End ...
 func (sp SendDocument) End() ResultWithMessage {
-	return sp.Send.Bot.SendDocument(sp.Send.ChatID, sp.Document, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.sendDocument(sp.Send.ChatID, sp.Document, sp.Options)
 }
 
 // SendSticker ...
 type SendSticker struct {
-	Send             *Send
-	Sticker          interface{}
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
-}
-
-// ReplyToMessage ...
-func (sp *SendSticker) ReplyToMessage(rm int) *SendSticker {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendSticker) Keyboard(kb ReplyKeyboardMarkup) *SendSticker {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendSticker) KeyboardHide(kb ReplyKeyboardHide) *SendSticker {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// ForceReply ...
-func (sp *SendSticker) ForceReply(fr ForceReply) *SendSticker {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
+	SendCommon[SendSticker]
+	Sticker interface{}
 }
 
 // End ...
 func (sp SendSticker) End() ResultWithMessage {
-	return sp.Send.Bot.SendSticker(sp.Send.ChatID, sp.Sticker, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.sendSticker(sp.Send.ChatID, sp.Sticker, sp.Options)
 }
 
 // SendVideo ...
 type SendVideo struct {
-	Send             *Send
-	Video            string
-	CaptionField     *string
-	DurationField    *int
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
-}
-
-// ReplyToMessage ...
-func (sp *SendVideo) ReplyToMessage(rm int) *SendVideo {
-	sp.ReplyToMessageID = &rm
-	return sp
+	SendCommon[SendVideo]
+	Video         string
+	CaptionField  *string
+	ParseModeS    *ParseModeT
+	DurationField *int
 }
 
 // Caption ...
@@ -391,45 +319,42 @@ func (sp *SendVideo) Caption(cap string) *SendVideo {
 	return sp
 }
 
-// Duration ...
-func (sp *SendVideo) Duration(dur int) *SendVideo {
-	sp.DurationField = &dur
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendVideo) Keyboard(kb ReplyKeyboardMarkup) *SendVideo {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
+// ParseMode sets the parse mode of the caption.
+func (sp *SendVideo) ParseMode(pm ParseModeT) *SendVideo {
+	sp.ParseModeS = &pm
 	return sp
 }
 
-// KeyboardHide ...
-func (sp *SendVideo) KeyboardHide(kb ReplyKeyboardHide) *SendVideo {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
+func (sp SendVideo) caption() FormattedText {
+	if sp.CaptionField == nil {
+		return nil
+	}
+	return formattedText{*sp.CaptionField, sp.ParseModeS, nil}
 }
 
-// ForceReply ...
-func (sp *SendVideo) ForceReply(fr ForceReply) *SendVideo {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
+// Duration ...
+func (sp *SendVideo) Duration(dur int) *SendVideo {
+	sp.DurationField = &dur
 	return sp
 }
 
 // End ...
 func (sp SendVideo) End() ResultWithMessage {
-	return sp.Send.Bot.SendVideo(sp.Send.ChatID, sp.Video, sp.CaptionField, sp.DurationField, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.sendVideo(sp.Send.ChatID, sp.Video, sp.caption(), sp.DurationField, sp.Options)
+}
+
+// EndSplit sends the media, if the caption is too long the rest is sent in messages after it, with the keyboard in the last one.
+func (sp SendVideo) EndSplit() ([]Message, error) {
+	return sp.Send.Bot.sendWithLongCaption(sp.Send.ChatID, sp.caption(), sp.Options, func(caption FormattedText, opts SendOptions) ResultWithMessage {
+		return sp.Send.Bot.sendVideo(sp.Send.ChatID, sp.Video, caption, sp.DurationField, opts)
+	})
 }
 
 // SendLocation ...
 type SendLocation struct {
-	Send             *Send
-	Latitude         float64
-	Longitude        float64
-	ReplyToMessageID *int
-	ReplyMarkup      *ReplyMarkupInt
+	SendCommon[SendLocation]
+	Latitude  float64
+	Longitude float64
 }
 
 // SetLatitude ...
@@ -444,36 +369,9 @@ func (sp *SendLocation) SetLongitude(long float64) *SendLocation {
 	return sp
 }
 
-// ReplyToMessage ...
-func (sp *SendLocation) ReplyToMessage(rm int) *SendLocation {
-	sp.ReplyToMessageID = &rm
-	return sp
-}
-
-// Keyboard ...
-func (sp *SendLocation) Keyboard(kb ReplyKeyboardMarkup) *SendLocation {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// KeyboardHide ...
-func (sp *SendLocation) KeyboardHide(kb ReplyKeyboardHide) *SendLocation {
-	var rmi ReplyMarkupInt = kb
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
-// ForceReply ...
-func (sp *SendLocation) ForceReply(fr ForceReply) *SendLocation {
-	var rmi ReplyMarkupInt = fr
-	sp.ReplyMarkup = &rmi
-	return sp
-}
-
 // End ...
 func (sp SendLocation) End() ResultWithMessage {
-	return sp.Send.Bot.SendLocation(sp.Send.ChatID, sp.Latitude, sp.Longitude, sp.ReplyToMessageID, sp.ReplyMarkup)
+	return sp.Send.Bot.SendLocationQuery(SendLocationQuery{sp.Send.ChatID, sp.Latitude, sp.Longitude, sp.Options})
 }
 
 // SendChatAction ...
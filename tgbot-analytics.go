@@ -0,0 +1,216 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AnalyticsEvent is an update received or a message sent by the bot.
+type AnalyticsEvent struct {
+	Time     time.Time   `json:"time"`
+	Kind     string      `json:"kind"` // "update" or "outgoing"
+	BotID    int         `json:"bot_id"`
+	ChatID   int         `json:"chat_id,omitempty"`
+	UpdateID int         `json:"update_id,omitempty"`
+	Name     string      `json:"name"`           // "text:<text>" or "other" for the updates, the method for the outgoing messages
+	Data     interface{} `json:"data,omitempty"` // The Message, or the payload if Telegram didn't return it
+}
+
+// Analytics receives the events of the bot, it's called in the goroutine of the handlers so it shouldn't block,
+// wrap slow ones with BatchAnalytics.
+type Analytics interface {
+	Track(ev AnalyticsEvent)
+}
+
+// AnalyticsFunc is a function used as Analytics.
+type AnalyticsFunc func(ev AnalyticsEvent)
+
+// Track ...
+func (f AnalyticsFunc) Track(ev AnalyticsEvent) {
+	f(ev)
+}
+
+// SetAnalytics sets where the bot sends the updates received and the messages sent, with polling and with webhooks.
+func (bot *TgBot) SetAnalytics(a Analytics) *TgBot {
+	bot.Analytics = a
+	return bot
+}
+
+func (bot TgBot) trackUpdate(input MessageWithUpdateID) {
+	if bot.Analytics == nil {
+		return
+	}
+	name := "other"
+	if input.Msg.Text != nil {
+		name = fmt.Sprintf("text:%s", *input.Msg.Text)
+	}
+	bot.Analytics.Track(AnalyticsEvent{
+		Time:     time.Now(),
+		Kind:     "update",
+		BotID:    bot.ID,
+		ChatID:   input.Msg.Chat.ID,
+		UpdateID: input.UpdateID,
+		Name:     name,
+		Data:     input.Msg,
+	})
+}
+
+func (bot TgBot) trackOutgoing(method string, res ResultWithMessage, payload interface{}) {
+	if bot.Analytics == nil || !res.Ok {
+		return
+	}
+	ev := AnalyticsEvent{
+		Time:  time.Now(),
+		Kind:  "outgoing",
+		BotID: bot.ID,
+		Name:  method,
+		Data:  payload,
+	}
+	if res.Result != nil {
+		ev.ChatID = res.Result.Chat.ID
+		ev.Data = *res.Result
+	}
+	bot.Analytics.Track(ev)
+}
+
+// BatchAnalytics collects the events in the background and gives them to Flush in batches,
+// when Size events are waiting or every Interval. If more than Buffer events are waiting the new ones are dropped.
+type BatchAnalytics struct {
+	Flush   func([]AnalyticsEvent) error
+	OnError func(error) // Called when Flush fails, the batch is discarded
+
+	events    chan AnalyticsEvent
+	size      int
+	interval  time.Duration
+	dropped   int64
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewBatchAnalytics starts a BatchAnalytics, call Close to send the last events.
+// size and interval default to 100 events and 5 seconds, and it keeps up to 10 times size events waiting.
+func NewBatchAnalytics(flush func([]AnalyticsEvent) error, size int, interval time.Duration) *BatchAnalytics {
+	if size <= 0 {
+		size = 100
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ba := &BatchAnalytics{
+		Flush:    flush,
+		events:   make(chan AnalyticsEvent, size*10),
+		size:     size,
+		interval: interval,
+		closed:   make(chan struct{}),
+	}
+	go ba.loop()
+	return ba
+}
+
+// Track queues the event without blocking.
+func (ba *BatchAnalytics) Track(ev AnalyticsEvent) {
+	select {
+	case ba.events <- ev:
+	default:
+		atomic.AddInt64(&ba.dropped, 1)
+	}
+}
+
+// Dropped returns how many events were dropped because the buffer was full.
+func (ba *BatchAnalytics) Dropped() int64 {
+	return atomic.LoadInt64(&ba.dropped)
+}
+
+// Close sends the waiting events and stops, Track must not be called after it.
+func (ba *BatchAnalytics) Close() {
+	ba.closeOnce.Do(func() {
+		close(ba.events)
+	})
+	<-ba.closed
+}
+
+func (ba *BatchAnalytics) loop() {
+	defer close(ba.closed)
+	ticker := time.NewTicker(ba.interval)
+	defer ticker.Stop()
+
+	batch := make([]AnalyticsEvent, 0, ba.size)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := ba.Flush(batch); err != nil && ba.OnError != nil {
+			ba.OnError(err)
+		}
+		batch = make([]AnalyticsEvent, 0, ba.size)
+	}
+
+	for {
+		select {
+		case ev, ok := <-ba.events:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) >= ba.size {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// JSONLAnalytics writes every event as a JSON line.
+type JSONLAnalytics struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewJSONLAnalytics writes the events to w.
+func NewJSONLAnalytics(w io.Writer) *JSONLAnalytics {
+	return &JSONLAnalytics{enc: json.NewEncoder(w)}
+}
+
+// OpenJSONLAnalytics appends the events to the file, creating it if it doesn't exist.
+func OpenJSONLAnalytics(name string) (*JSONLAnalytics, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	ja := NewJSONLAnalytics(f)
+	ja.c = f
+	return ja, nil
+}
+
+// Track ...
+func (ja *JSONLAnalytics) Track(ev AnalyticsEvent) {
+	ja.WriteBatch([]AnalyticsEvent{ev})
+}
+
+// WriteBatch writes the events, use it as the Flush of a BatchAnalytics.
+func (ja *JSONLAnalytics) WriteBatch(events []AnalyticsEvent) error {
+	ja.mu.Lock()
+	defer ja.mu.Unlock()
+	for _, ev := range events {
+		if err := ja.enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file if it was opened with OpenJSONLAnalytics.
+func (ja *JSONLAnalytics) Close() error {
+	if ja.c == nil {
+		return nil
+	}
+	return ja.c.Close()
+}
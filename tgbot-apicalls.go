@@ -2,63 +2,48 @@ package tgbot
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
 	"image/gif"
 	"io"
-	"strings"
-
-	"github.com/rockneurotiko/gorequest"
+	"net/http"
 )
 
 // GetMe Call getMe path
 func (bot TgBot) GetMe() (User, error) {
-	body, err := getPetition(bot.buildPath("getMe"), nil)
-
+	user, err := Call[User](bot.Context(), bot, "getMe", nil)
 	if err != nil {
-		return User{}, err
-	}
-
-	var data ResultGetUser
-	dec := json.NewDecoder(strings.NewReader(body))
-	dec.Decode(&data)
-
-	if !data.Ok {
-		errc := 403
-		desc := ""
-		if data.ErrorCode != nil {
-			errc = *data.ErrorCode
-		}
-		if data.Description != nil {
-			desc = *data.Description
-		}
-
-		errormsg := fmt.Sprintf("Some error happened, maybe your token is bad:\nError code: %d\nDescription: %s\nToken: %s", errc, desc, bot.Token)
-		return User{}, errors.New(errormsg)
+		return User{}, fmt.Errorf("Some error happened, maybe your token is bad:\n%w", err)
 	}
-	return data.Result, nil
+	return user, nil
 }
 
-// GetUpdates call getUpdates
+// GetUpdates call getUpdates with the bot polling options and the current offset.
 func (bot TgBot) GetUpdates() ([]MessageWithUpdateID, error) {
-	timeoutreq := fmt.Sprintf("timeout=%d", timeout)
-	lastid := fmt.Sprintf("offset=%d", bot.LastUpdateID+1)
-
-	body, err := getPetition(bot.buildPath("getUpdates"), []string{timeoutreq, lastid})
+	offset := int(bot.committedOffset() + 1)
+	tout := timeout
+	if bot.Polling.Timeout != nil {
+		tout = *bot.Polling.Timeout
+	}
+	q := GetUpdatesQuery{Offset: &offset, Timeout: &tout, AllowedUpdates: bot.Polling.AllowedUpdates}
+	if bot.Polling.Limit > 0 {
+		limit := bot.Polling.Limit
+		q.Limit = &limit
+	}
+	if q.AllowedUpdates == nil {
+		q.AllowedUpdates = bot.AllowedUpdates()
+	}
+	return bot.GetUpdatesQuery(q)
+}
 
+// GetUpdatesQuery call getUpdates with the query.
+func (bot TgBot) GetUpdatesQuery(q GetUpdatesQuery) ([]MessageWithUpdateID, error) {
+	updates, err := Call[[]MessageWithUpdateID](bot.Context(), bot, "getUpdates", q)
 	if err != nil {
 		return []MessageWithUpdateID{}, err
 	}
-
-	var data ResultGetUpdates
-	json.Unmarshal([]byte(body), &data)
-
-	if !data.Ok {
-		return []MessageWithUpdateID{}, errors.New("Some error happened in your petition, check your token or remove the webhook.")
-	}
-	return data.Result, nil
+	return updates, nil
 }
 
 // SetWebhook call the setWebhook API method with the URL suplied, will return the result or an error (the error will be sended if the webhook can't be setted)
@@ -80,33 +65,14 @@ func (bot TgBot) SetWebhookWithCert(url string, cert string) ResultSetWebhook {
 		return ResultSetWebhook{false, "Your certificate is not a valid path", &r, &errc}
 	}
 
-	urlsend := bot.buildPath("setWebhook")
-
-	bytes, err := bot.uploadFileNoResult(urlsend, map[string]string{"url": url}, "certificate", cert)
-	if err != nil {
-		errc := 500
-		return ResultSetWebhook{false, "Some error happened", nil, &errc}
-	}
-	var apiResp ResultSetWebhook
-	json.Unmarshal(bytes, &apiResp)
-
-	return apiResp
+	ok, err := Call[bool](bot.Context(), bot, "setWebhook", SetWebhookCertQuery{url, cert})
+	return webhookResult(ok, err)
 }
 
 // SetWebhookNoQuery ...
 func (bot TgBot) SetWebhookNoQuery(urlw string) ResultSetWebhook {
-	q := SetWebhookQuery{&urlw}
-	url := bot.buildPath("setWebhook")
-	body, error := postPetition(url, q, nil)
-
-	if error != nil {
-		errc := 500
-		err := "Some error happened while sending the message"
-		return ResultSetWebhook{false, err, nil, &errc}
-	}
-	var result ResultSetWebhook
-	json.Unmarshal([]byte(body), &result)
-	return result
+	ok, err := Call[bool](bot.Context(), bot, "setWebhook", SetWebhookQuery{URL: &urlw})
+	return webhookResult(ok, err)
 }
 
 // SetWebhookQuery raw method that uses the struct to send the petition.
@@ -117,6 +83,48 @@ func (bot TgBot) SetWebhookQuery(url *string, cert *string) ResultSetWebhook {
 	return bot.SetWebhookWithCert(*url, *cert)
 }
 
+// SetWebhookWithOptions call setWebhook with all the options of the query, uploading the certificate if it has one.
+// If it works, the secret token is saved in the bot so the webhook handler can verify the updates.
+func (bot *TgBot) SetWebhookWithOptions(q SetWebhookQuery) ResultSetWebhook {
+	if q.URL == nil {
+		// The empty URL removes the webhook
+		empty := ""
+		q.URL = &empty
+	}
+	ok, err := Call[bool](bot.Context(), *bot, "setWebhook", q)
+	if err == nil {
+		bot.WebhookSecret = ""
+		if q.SecretToken != nil {
+			bot.WebhookSecret = *q.SecretToken
+		}
+	}
+	return webhookResult(ok, err)
+}
+
+// DeleteWebhook call deleteWebhook, dropping the pending updates if you want.
+func (bot TgBot) DeleteWebhook(dropPending bool) (ResultSetWebhook, error) {
+	q := DeleteWebhookQuery{}
+	if dropPending {
+		q.DropPendingUpdates = &dropPending
+	}
+	ok, err := Call[bool](bot.Context(), bot, "deleteWebhook", q)
+	return webhookResult(ok, err), err
+}
+
+// webhookResult converts the answer of Call in the result of the webhook methods.
+func webhookResult(ok bool, err error) ResultSetWebhook {
+	if err != nil {
+		base := resultBase(err)
+		return ResultSetWebhook{false, *base.Description, nil, base.ErrorCode}
+	}
+	return ResultSetWebhook{true, "", &ok, nil}
+}
+
+// GetWebhookInfo call getWebhookInfo
+func (bot TgBot) GetWebhookInfo() (WebhookInfo, error) {
+	return Call[WebhookInfo](bot.Context(), bot, "getWebhookInfo", nil)
+}
+
 // GetUserProfilePhotos args will use only the two first parameters, the first one will be the limit of images to get, and the second will be the offset photo id.
 func (bot TgBot) GetUserProfilePhotos(uid int, args ...int) UserProfilePhotos {
 	pet := ResultWithUserProfilePhotos{}
@@ -140,25 +148,41 @@ func (bot TgBot) GetUserProfilePhotos(uid int, args ...int) UserProfilePhotos {
 
 // Send messages
 
+// replyOptions returns the options with the reply and the markup of the old style functions.
+func replyOptions(rtmid *int, rm *ReplyMarkupInt) SendOptions {
+	return SendOptions{ReplyToMessageID: rtmid, ReplyMarkup: rm}
+}
+
+// errorResult returns the result of a call that failed before reaching the API.
+func errorResult(err error) ResultWithMessage {
+	return ResultWithMessage{resultBase(err), nil}
+}
+
 // SimpleSendMessage send a simple text message.
 func (bot TgBot) SimpleSendMessage(msg Message, text string) (res Message, err error) {
 	ressm := bot.SendMessage(msg.Chat.ID, text, nil, nil, nil, nil)
 	return splitResultInMessageError(ressm)
 }
 
-// SendMessageWithKeyboard send a message with explicit Keyboard
+// SendMessageWithKeyboard send a message with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendMessage with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendMessageWithKeyboard(cid int, text string, parsemode *ParseModeT, dwp *bool, rtmid *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendMessage(cid, text, parsemode, dwp, rtmid, &rkm)
 }
 
-// SendMessageWithForceReply send a message with explicit Force Reply.
+// SendMessageWithForceReply send a message with explicit ForceReply.
+//
+// Deprecated: use SendMessage with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendMessageWithForceReply(cid int, text string, parsemode *ParseModeT, dwp *bool, rtmid *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendMessage(cid, text, parsemode, dwp, rtmid, &rkm)
 }
 
-// SendMessageWithKeyboardHide send a message with explicit Keyboard Hide.
+// SendMessageWithKeyboardHide send a message with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendMessage with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendMessageWithKeyboardHide(cid int, text string, parsemode *ParseModeT, dwp *bool, rtmid *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendMessage(cid, text, parsemode, dwp, rtmid, &rkm)
@@ -166,35 +190,40 @@ func (bot TgBot) SendMessageWithKeyboardHide(cid int, text string, parsemode *Pa
 
 // SendMessage full function wrapper for sendMessage, uses the markup interface
 func (bot TgBot) SendMessage(cid int, text string, parsemode *ParseModeT, dwp *bool, rtmid *int, rm *ReplyMarkupInt) ResultWithMessage {
+	return bot.sendMessage(cid, formattedText{text, parsemode, nil}, dwp, replyOptions(rtmid, rm))
+}
+
+// SendFormattedMessage sends the formatted text, with its parse mode or its entities.
+func (bot TgBot) SendFormattedMessage(cid int, ft FormattedText, dwp *bool, rtmid *int, rm *ReplyMarkupInt) ResultWithMessage {
+	return bot.sendMessage(cid, ft, dwp, replyOptions(rtmid, rm))
+}
+
+func (bot TgBot) sendMessage(cid int, ft FormattedText, dwp *bool, opts SendOptions) ResultWithMessage {
 	var pm *string = nil
-	if parsemode != nil {
+	if parsemode := ft.ParseMode(); parsemode != nil {
 		pmt := parsemode.String()
 		pm = &pmt
 	}
-	payload := QuerySendMessage{cid, text, pm, dwp, rtmid, rm}
+	payload := QuerySendMessage{cid, ft.Text(), pm, dwp, ft.Entities(), opts}
 	return bot.SendMessageQuery(payload)
 }
 
 // SendMessageQuery full sendMessage with the query.
 func (bot TgBot) SendMessageQuery(payload QuerySendMessage) ResultWithMessage {
-	url := bot.buildPath("sendMessage")
-	hookPayload(&payload, bot.DefaultOptions)
-	return bot.genericSendPostData(url, payload)
+	return bot.callMessage("sendMessage", &payload)
 }
 
 // Forward Message!!
 
 // ForwardMessage full function wrapper for forwardMessage
 func (bot TgBot) ForwardMessage(cid int, fid int, mid int) ResultWithMessage {
-	payload := ForwardMessageQuery{cid, fid, mid}
+	payload := ForwardMessageQuery{cid, fid, mid, SendOptions{}}
 	return bot.ForwardMessageQuery(payload)
 }
 
 // ForwardMessageQuery  full forwardMessage call
 func (bot TgBot) ForwardMessageQuery(payload ForwardMessageQuery) ResultWithMessage {
-	url := bot.buildPath("forwardMessage")
-	hookPayload(&payload, bot.DefaultOptions)
-	return bot.genericSendPostData(url, payload)
+	return bot.callMessage("forwardMessage", &payload)
 }
 
 // Send photo!!
@@ -206,19 +235,25 @@ func (bot TgBot) SimpleSendPhoto(msg Message, photo interface{}) (res Message, e
 	return splitResultInMessageError(ressm)
 }
 
-// SendPhotoWithKeyboard send a photo with explicit Keyboard
+// SendPhotoWithKeyboard send a photo with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendPhoto with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendPhotoWithKeyboard(cid int, photo interface{}, caption *string, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendPhoto(cid, photo, caption, rmi, &rkm)
 }
 
-// SendPhotoWithForceReply send a photo with explicit Force Reply.
+// SendPhotoWithForceReply send a photo with explicit ForceReply.
+//
+// Deprecated: use SendPhoto with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendPhotoWithForceReply(cid int, photo interface{}, caption *string, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendPhoto(cid, photo, caption, rmi, &rkm)
 }
 
-// SendPhotoWithKeyboardHide send a photo with explicit Keyboard Hide.
+// SendPhotoWithKeyboardHide send a photo with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendPhoto with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendPhotoWithKeyboardHide(cid int, photo interface{}, caption *string, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendPhoto(cid, photo, caption, rmi, &rkm)
@@ -226,41 +261,38 @@ func (bot TgBot) SendPhotoWithKeyboardHide(cid int, photo interface{}, caption *
 
 // SendPhoto full function wrapper for sendPhoto, use the markup interface.
 func (bot TgBot) SendPhoto(cid int, photo interface{}, caption *string, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	payload, err := bot.imageInterfaceToType(cid, photo, caption, rmi, rm)
+	return bot.sendPhoto(cid, photo, plainCaption(caption), replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendPhoto(cid int, photo interface{}, caption FormattedText, opts SendOptions) ResultWithMessage {
+	payload, err := bot.imageInterfaceToType(cid, photo, caption, opts)
 	if err != nil {
-		errc := 500
-		errs := err.Error()
-		return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
+		return errorResult(err)
 	}
 	return bot.SendPhotoQuery(payload)
 }
 
-func imageStringToPayload(cid int, caption *string, rmi *int, rm *ReplyMarkupInt, pars string) (payload interface{}) {
-	payload = SendPhotoIDQuery{cid, pars, caption, rmi, rm}
+func imageStringToPayload(cid int, caption FormattedText, opts SendOptions, pars string) (payload interface{}) {
+	text, pm, entities := captionFields(caption)
+	payload = &SendPhotoIDQuery{cid, pars, text, pm, entities, opts}
 	if looksLikePath(pars) {
-		payload = SendPhotoPathQuery{cid, pars, caption, rmi, rm}
+		payload = &SendPhotoPathQuery{cid, pars, text, pm, entities, opts}
 	}
 	return payload
 }
 
-func (bot TgBot) imageInterfaceToType(cid int, photo interface{}, caption *string, rmi *int, rm *ReplyMarkupInt) (payload interface{}, err error) {
+func (bot TgBot) imageInterfaceToType(cid int, photo interface{}, caption FormattedText, opts SendOptions) (payload interface{}, err error) {
 	switch pars := photo.(type) {
 	case []byte:
-		payload = imageStringToPayload(cid, caption, rmi, rm, string(pars))
+		payload = imageStringToPayload(cid, caption, opts, string(pars))
 	case *bytes.Buffer:
-		payload = imageStringToPayload(cid, caption, rmi, rm, string(pars.Bytes()))
+		payload = imageStringToPayload(cid, caption, opts, string(pars.Bytes()))
 	case string:
-		payload = imageStringToPayload(cid, caption, rmi, rm, pars)
+		payload = imageStringToPayload(cid, caption, opts, pars)
 	case image.Image:
-		mp := struct {
-			ChatID           int             `json:"chat_id"`
-			Photo            image.Image     `json:"photo"`
-			Caption          *string         `json:"caption,omitempty"`
-			ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-			ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
-		}{cid, pars, caption, rmi, rm}
-		hookPayload(&mp, bot.DefaultOptions)
-		payload = mp
+		text, pm, entities := captionFields(caption)
+		query := &SendPhotoIDQuery{ChatID: cid, Caption: text, ParseMode: pm, CaptionEntities: entities, SendOptions: opts}
+		payload = fileParams{query, []InputFile{{"photo", pars}}}
 	default:
 		err = errors.New("No struct interface detected")
 	}
@@ -269,35 +301,36 @@ func (bot TgBot) imageInterfaceToType(cid int, photo interface{}, caption *strin
 
 // SendPhotoQuery full function that uses the query.
 func (bot TgBot) SendPhotoQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendPhoto", "Photo", "photo", payload)
+	return bot.callMessage("sendPhoto", payload)
 }
 
 // Audio!!
 
 // SimpleSendAudio send just an audio
 func (bot TgBot) SimpleSendAudio(msg Message, audio string) (res Message, err error) {
-	cid := msg.Chat.ID
-	var payload interface{} = SendAudioIDQuery{cid, audio, nil, nil, nil, nil, nil}
-	if looksLikePath(audio) {
-		payload = SendAudioPathQuery{cid, audio, nil, nil, nil, nil, nil}
-	}
-	ressm := bot.SendAudioQuery(payload)
+	ressm := bot.SendAudio(msg.Chat.ID, audio, nil, nil, nil, nil, nil)
 	return splitResultInMessageError(ressm)
 }
 
-// SendAudioWithKeyboard send a audio with explicit Keyboard
+// SendAudioWithKeyboard send an audio with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendAudio with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendAudioWithKeyboard(cid int, audio string, duration *int, performer *string, title *string, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendAudio(cid, audio, duration, performer, title, rmi, &rkm)
 }
 
-// SendAudioWithForceReply send a audio with explicit Force Reply.
+// SendAudioWithForceReply send an audio with explicit ForceReply.
+//
+// Deprecated: use SendAudio with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendAudioWithForceReply(cid int, audio string, duration *int, performer *string, title *string, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendAudio(cid, audio, duration, performer, title, rmi, &rkm)
 }
 
-// SendAudioWithKeyboardHide send a audio with explicit Keyboard Hide.
+// SendAudioWithKeyboardHide send an audio with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendAudio with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendAudioWithKeyboardHide(cid int, audio string, duration *int, performer *string, title *string, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendAudio(cid, audio, duration, performer, title, rmi, &rkm)
@@ -305,44 +338,49 @@ func (bot TgBot) SendAudioWithKeyboardHide(cid int, audio string, duration *int,
 
 // SendAudio full function to send an audio. Uses the reply markup interface.
 func (bot TgBot) SendAudio(cid int, audio string, duration *int, performer *string, title *string, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	var payload interface{} = SendAudioIDQuery{cid, audio, duration, performer, title, rmi, rm}
+	return bot.sendAudio(cid, audio, duration, performer, title, replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendAudio(cid int, audio string, duration *int, performer *string, title *string, opts SendOptions) ResultWithMessage {
+	var payload interface{} = &SendAudioIDQuery{cid, audio, duration, performer, title, opts}
 	if looksLikePath(audio) {
-		payload = SendAudioPathQuery{cid, audio, duration, performer, title, rmi, rm}
+		payload = &SendAudioPathQuery{cid, audio, duration, performer, title, opts}
 	}
 	return bot.SendAudioQuery(payload)
 }
 
 // SendAudioQuery full function using the query.
 func (bot TgBot) SendAudioQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendAudio", "Audio", "audio", payload)
+	return bot.callMessage("sendAudio", payload)
 }
 
 // Voice!!
 
 // SimpleSendVoice send just an audio
 func (bot TgBot) SimpleSendVoice(msg Message, audio string) (res Message, err error) {
-	cid := msg.Chat.ID
-	var payload interface{} = SendVoiceIDQuery{cid, audio, nil, nil, nil}
-	if looksLikePath(audio) {
-		payload = SendVoicePathQuery{cid, audio, nil, nil, nil}
-	}
-	ressm := bot.SendVoiceQuery(payload)
+	ressm := bot.SendVoice(msg.Chat.ID, audio, nil, nil, nil)
 	return splitResultInMessageError(ressm)
 }
 
-// SendVoiceWithKeyboard send a audio with explicit Keyboard
+// SendVoiceWithKeyboard send a voice with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendVoice with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendVoiceWithKeyboard(cid int, audio string, duration *int, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVoice(cid, audio, duration, rmi, &rkm)
 }
 
-// SendVoiceWithForceReply send a audio with explicit Force Reply.
+// SendVoiceWithForceReply send a voice with explicit ForceReply.
+//
+// Deprecated: use SendVoice with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendVoiceWithForceReply(cid int, audio string, duration *int, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVoice(cid, audio, duration, rmi, &rkm)
 }
 
-// SendVoiceWithKeyboardHide send a audio with explicit Keyboard Hide.
+// SendVoiceWithKeyboardHide send a voice with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendVoice with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendVoiceWithKeyboardHide(cid int, audio string, duration *int, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVoice(cid, audio, duration, rmi, &rkm)
@@ -350,44 +388,49 @@ func (bot TgBot) SendVoiceWithKeyboardHide(cid int, audio string, duration *int,
 
 // SendVoice full function to send an audio. Uses the reply markup interface.
 func (bot TgBot) SendVoice(cid int, audio string, duration *int, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	var payload interface{} = SendVoiceIDQuery{cid, audio, duration, rmi, rm}
+	return bot.sendVoice(cid, audio, duration, replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendVoice(cid int, audio string, duration *int, opts SendOptions) ResultWithMessage {
+	var payload interface{} = &SendVoiceIDQuery{cid, audio, duration, opts}
 	if looksLikePath(audio) {
-		payload = SendVoicePathQuery{cid, audio, nil, rmi, rm}
+		payload = &SendVoicePathQuery{cid, audio, nil, opts}
 	}
 	return bot.SendVoiceQuery(payload)
 }
 
 // SendVoiceQuery full function using the query.
 func (bot TgBot) SendVoiceQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendVoice", "Voice", "voice", payload)
+	return bot.callMessage("sendVoice", payload)
 }
 
 //Documents!!
 
 // SimpleSendDocument send just a document.
 func (bot TgBot) SimpleSendDocument(msg Message, document string) (res Message, err error) {
-	cid := msg.Chat.ID
-	var payload interface{} = SendDocumentIDQuery{cid, document, nil, nil}
-	if looksLikePath(document) {
-		payload = SendDocumentPathQuery{cid, document, nil, nil}
-	}
-	ressm := bot.SendDocumentQuery(payload)
+	ressm := bot.SendDocument(msg.Chat.ID, document, nil, nil)
 	return splitResultInMessageError(ressm)
 }
 
-// SendDocumentWithKeyboard send a document with explicit keyboard.
+// SendDocumentWithKeyboard send a document with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendDocument with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendDocumentWithKeyboard(cid int, document string, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendDocument(cid, document, rmi, &rkm)
 }
 
-// SendDocumentWithForceReply send a document with explicit force reply
+// SendDocumentWithForceReply send a document with explicit ForceReply.
+//
+// Deprecated: use SendDocument with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendDocumentWithForceReply(cid int, document string, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendDocument(cid, document, rmi, &rkm)
 }
 
-// SendDocumentWithKeyboardHide send a document with explicit keyboard hide.
+// SendDocumentWithKeyboardHide send a document with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendDocument with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendDocumentWithKeyboardHide(cid int, document string, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendDocument(cid, document, rmi, &rkm)
@@ -395,70 +438,31 @@ func (bot TgBot) SendDocumentWithKeyboardHide(cid int, document string, rmi *int
 
 // SendDocument full function to send document, uses the reply markup interface.
 func (bot TgBot) SendDocument(cid int, document interface{}, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	payload, err := bot.documentInterfaceToType(cid, document, rmi, rm)
+	return bot.sendDocument(cid, document, replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendDocument(cid int, document interface{}, opts SendOptions) ResultWithMessage {
+	payload, err := bot.documentInterfaceToType(cid, document, opts)
 	if err != nil {
-		errc := 500
-		errs := err.Error()
-		return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
+		return errorResult(err)
 	}
-	// var payload interface{} = SendDocumentIDQuery{cid, document, rmi, rm}
-	// if looksLikePath(document) {
-	// 	payload = SendDocumentPathQuery{cid, document, rmi, rm}
-	// }
 	return bot.SendDocumentQuery(payload)
 }
 
-// func (bot TgBot) SendDocumentImageTest(cid int, payload interface{}) ResultWithMessage {
-// 	payload, err := bot.documentInterfaceToType(cid, payload, nil, nil)
-// 	if err != nil {
-// 		errc := 500
-// 		errs := err.Error()
-// 		return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
-// 	}
-// 	return bot.SendDocumentQuery(payload)
-// }
-
 type ReaderSender struct {
 	Read io.Reader
 	Name string
 }
 
-func (bot TgBot) documentInterfaceToType(cid int, photo interface{}, rmi *int, rm *ReplyMarkupInt) (payload interface{}, err error) {
+func (bot TgBot) documentInterfaceToType(cid int, photo interface{}, opts SendOptions) (payload interface{}, err error) {
 	switch pars := photo.(type) {
 	case string:
-		payload = SendDocumentIDQuery{cid, pars, rmi, rm}
+		payload = &SendDocumentIDQuery{cid, pars, opts}
 		if looksLikePath(pars) {
-			payload = SendDocumentPathQuery{cid, pars, rmi, rm}
+			payload = &SendDocumentPathQuery{cid, pars, opts}
 		}
-	case ReaderSender:
-		mp := struct {
-			ChatID           int             `json:"chat_id"`
-			Document         ReaderSender    `json:"document"`
-			ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-			ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
-		}{cid, pars, rmi, rm}
-		hookPayload(&mp, bot.DefaultOptions)
-		payload = mp
-	case image.Image:
-		{
-			mp := struct {
-				ChatID           int             `json:"chat_id"`
-				Document         image.Image     `json:"document"`
-				ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-				ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
-			}{cid, pars, rmi, rm}
-			hookPayload(&mp, bot.DefaultOptions)
-			payload = mp
-		}
-	case *gif.GIF:
-		mp := struct {
-			ChatID           int             `json:"chat_id"`
-			Document         *gif.GIF        `json:"document"`
-			ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-			ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
-		}{cid, pars, rmi, rm}
-		hookPayload(&mp, bot.DefaultOptions)
-		payload = mp
+	case ReaderSender, image.Image, *gif.GIF:
+		payload = fileParams{&SendDocumentIDQuery{ChatID: cid, SendOptions: opts}, []InputFile{{"document", pars}}}
 	default:
 		err = errors.New("No struct interface detected")
 	}
@@ -467,7 +471,7 @@ func (bot TgBot) documentInterfaceToType(cid int, photo interface{}, rmi *int, r
 
 // SendDocumentQuery full function using the query.
 func (bot TgBot) SendDocumentQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendDocument", "Document", "document", payload)
+	return bot.callMessage("sendDocument", payload)
 }
 
 // Stickers!!!
@@ -479,19 +483,25 @@ func (bot TgBot) SimpleSendSticker(msg Message, sticker interface{}) (res Messag
 	return splitResultInMessageError(ressm)
 }
 
-// SendStickerWithKeyboard send a sticker with explicit keyboard.
+// SendStickerWithKeyboard send a sticker with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendSticker with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendStickerWithKeyboard(cid int, sticker interface{}, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendSticker(cid, sticker, rmi, &rkm)
 }
 
-// SendStickerWithForceReply send a sticker with explicit force reply.
+// SendStickerWithForceReply send a sticker with explicit ForceReply.
+//
+// Deprecated: use SendSticker with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendStickerWithForceReply(cid int, sticker interface{}, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendSticker(cid, sticker, rmi, &rkm)
 }
 
-// SendStickerWithKeyboardHide send a sticker with explicit keyboad hide.
+// SendStickerWithKeyboardHide send a sticker with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendSticker with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendStickerWithKeyboardHide(cid int, sticker interface{}, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendSticker(cid, sticker, rmi, &rkm)
@@ -499,29 +509,26 @@ func (bot TgBot) SendStickerWithKeyboardHide(cid int, sticker interface{}, rmi *
 
 // SendSticker full function to send a sticker, uses reply markup interface.
 func (bot TgBot) SendSticker(cid int, sticker interface{}, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	payload, err := bot.stickerInterfaceToType(cid, sticker, rmi, rm)
+	return bot.sendSticker(cid, sticker, replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendSticker(cid int, sticker interface{}, opts SendOptions) ResultWithMessage {
+	payload, err := bot.stickerInterfaceToType(cid, sticker, opts)
 	if err != nil {
-		errc := 500
-		errs := err.Error()
-		return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
+		return errorResult(err)
 	}
 	return bot.SendStickerQuery(payload)
 }
 
-func (bot TgBot) stickerInterfaceToType(cid int, sticker interface{}, rmi *int, rm *ReplyMarkupInt) (payload interface{}, err error) {
+func (bot TgBot) stickerInterfaceToType(cid int, sticker interface{}, opts SendOptions) (payload interface{}, err error) {
 	switch pars := sticker.(type) {
 	case string:
-		payload = SendStickerIDQuery{cid, pars, rmi, rm}
+		payload = &SendStickerIDQuery{cid, pars, opts}
 		if looksLikePath(pars) {
-			payload = SendStickerPathQuery{cid, pars, rmi, rm}
+			payload = &SendStickerPathQuery{cid, pars, opts}
 		}
 	case image.Image:
-		payload = struct {
-			ChatID           int             `json:"chat_id"`
-			Photo            image.Image     `json:"photo"`
-			ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-			ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
-		}{cid, pars, rmi, rm}
+		payload = fileParams{&SendStickerIDQuery{ChatID: cid, SendOptions: opts}, []InputFile{{"sticker", pars}}}
 	default:
 		err = errors.New("No struct interface detected")
 	}
@@ -530,35 +537,36 @@ func (bot TgBot) stickerInterfaceToType(cid int, sticker interface{}, rmi *int,
 
 // SendStickerQuery full function to send an sticker, uses the query.
 func (bot TgBot) SendStickerQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendSticker", "Sticker", "sticker", payload)
+	return bot.callMessage("sendSticker", payload)
 }
 
 // Send video!!!!
 
 // SimpleSendVideo just send a video from file path or id
 func (bot TgBot) SimpleSendVideo(msg Message, photo string) (res Message, err error) {
-	cid := msg.Chat.ID
-	var payload interface{} = SendVideoIDQuery{cid, photo, nil, nil, nil, nil}
-	if looksLikePath(photo) {
-		payload = SendVideoPathQuery{cid, photo, nil, nil, nil, nil}
-	}
-	ressm := bot.SendVideoQuery(payload)
+	ressm := bot.SendVideo(msg.Chat.ID, photo, nil, nil, nil, nil)
 	return splitResultInMessageError(ressm)
 }
 
-// SendVideoWithKeyboard send a video with explicit keyboard.
+// SendVideoWithKeyboard send a video with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendVideo with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendVideoWithKeyboard(cid int, photo string, caption *string, duration *int, rmi *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVideo(cid, photo, caption, duration, rmi, &rkm)
 }
 
-// SendVideoWithForceReply send a video with explicit force reply.
+// SendVideoWithForceReply send a video with explicit ForceReply.
+//
+// Deprecated: use SendVideo with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendVideoWithForceReply(cid int, photo string, caption *string, duration *int, rmi *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVideo(cid, photo, caption, duration, rmi, &rkm)
 }
 
-// SendVideoWithKeyboardHide send a video with explici keyboard hide.
+// SendVideoWithKeyboardHide send a video with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendVideo with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendVideoWithKeyboardHide(cid int, photo string, caption *string, duration *int, rmi *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendVideo(cid, photo, caption, duration, rmi, &rkm)
@@ -566,16 +574,21 @@ func (bot TgBot) SendVideoWithKeyboardHide(cid int, photo string, caption *strin
 
 // SendVideo full function to send a video.
 func (bot TgBot) SendVideo(cid int, photo string, caption *string, duration *int, rmi *int, rm *ReplyMarkupInt) ResultWithMessage {
-	var payload interface{} = SendVideoIDQuery{cid, photo, duration, caption, rmi, rm}
+	return bot.sendVideo(cid, photo, plainCaption(caption), duration, replyOptions(rmi, rm))
+}
+
+func (bot TgBot) sendVideo(cid int, photo string, caption FormattedText, duration *int, opts SendOptions) ResultWithMessage {
+	text, pm, entities := captionFields(caption)
+	var payload interface{} = &SendVideoIDQuery{cid, photo, duration, text, pm, entities, opts}
 	if looksLikePath(photo) {
-		payload = SendVideoPathQuery{cid, photo, duration, caption, rmi, rm}
+		payload = &SendVideoPathQuery{cid, photo, duration, text, pm, entities, opts}
 	}
 	return bot.SendVideoQuery(payload)
 }
 
 // SendVideoQuery full function to send video with query.
 func (bot TgBot) SendVideoQuery(payload interface{}) ResultWithMessage {
-	return bot.sendGenericQuery("sendVideo", "Video", "video", payload)
+	return bot.callMessage("sendVideo", payload)
 }
 
 // send Location!!!
@@ -586,19 +599,25 @@ func (bot TgBot) SimpleSendLocation(msg Message, latitude float64, longitude flo
 	return splitResultInMessageError(ressm)
 }
 
-// SendLocationWithKeyboard send a location with explicit keyboard.
+// SendLocationWithKeyboard send a location with explicit ReplyKeyboardMarkup.
+//
+// Deprecated: use SendLocation with the markup, or the Keyboard option of the Send builder.
 func (bot TgBot) SendLocationWithKeyboard(cid int, latitude float64, longitude float64, rtmid *int, rm ReplyKeyboardMarkup) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendLocation(cid, latitude, longitude, rtmid, &rkm)
 }
 
-// SendLocationWithForceReply send a location with explicit force reply.
+// SendLocationWithForceReply send a location with explicit ForceReply.
+//
+// Deprecated: use SendLocation with the markup, or the ForceReply option of the Send builder.
 func (bot TgBot) SendLocationWithForceReply(cid int, latitude float64, longitude float64, rtmid *int, rm ForceReply) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendLocation(cid, latitude, longitude, rtmid, &rkm)
 }
 
-// SendLocationWithKeyboardHide send a location with explicit keyboard hide.
+// SendLocationWithKeyboardHide send a location with explicit ReplyKeyboardHide.
+//
+// Deprecated: use SendLocation with the markup, or the KeyboardHide option of the Send builder.
 func (bot TgBot) SendLocationWithKeyboardHide(cid int, latitude float64, longitude float64, rtmid *int, rm ReplyKeyboardHide) ResultWithMessage {
 	var rkm ReplyMarkupInt = rm
 	return bot.SendLocation(cid, latitude, longitude, rtmid, &rkm)
@@ -606,15 +625,13 @@ func (bot TgBot) SendLocationWithKeyboardHide(cid int, latitude float64, longitu
 
 // SendLocation full function wrapper for sendLocation
 func (bot TgBot) SendLocation(cid int, latitude float64, longitude float64, rtmid *int, rm *ReplyMarkupInt) ResultWithMessage {
-	payload := SendLocationQuery{cid, latitude, longitude, rtmid, rm}
+	payload := SendLocationQuery{cid, latitude, longitude, replyOptions(rtmid, rm)}
 	return bot.SendLocationQuery(payload)
 }
 
 // SendLocationQuery full sendLocation call with query.
 func (bot TgBot) SendLocationQuery(payload SendLocationQuery) ResultWithMessage {
-	url := bot.buildPath("sendLocation")
-	hookPayload(&payload, bot.DefaultOptions)
-	return bot.genericSendPostData(url, payload)
+	return bot.callMessage("sendLocation", &payload)
 }
 
 // Send chat action!!!
@@ -631,55 +648,33 @@ func (bot TgBot) SendChatAction(cid int, ca ChatAction) {
 
 // SendChatActionQuery send an action query.
 func (bot TgBot) SendChatActionQuery(payload SendChatActionQuery) {
-	url := bot.buildPath("sendChatAction")
-	hookPayload(&payload, bot.DefaultOptions)
-	bot.genericSendPostData(url, payload)
+	if _, err := Call[bool](bot.Context(), bot, "sendChatAction", &payload); err == nil {
+		bot.trackOutgoing("sendChatAction", ResultWithMessage{resultBase(nil), nil}, payload)
+	}
 }
 
 // GetUserProfilePhotosQuery raw method that uses the struct to send the petition.
 func (bot TgBot) GetUserProfilePhotosQuery(quer GetUserProfilePhotosQuery) ResultWithUserProfilePhotos {
-	url := bot.buildPath("getUserProfilePhotos")
-	body, error := postPetition(url, quer, nil)
-
-	if error != nil {
-		errc := 500
-		err := "Some error happened while sending the message"
-		return ResultWithUserProfilePhotos{ResultBase{false, &errc, &err}, nil}
-	}
-	var result ResultWithUserProfilePhotos
-	json.Unmarshal([]byte(body), &result)
-	return result
+	photos, err := Call[*UserProfilePhotos](bot.Context(), bot, "getUserProfilePhotos", quer)
+	return ResultWithUserProfilePhotos{resultBase(err), photos}
 }
 
 func (bot TgBot) GetFile(id string) ResultWithGetFile {
-	url := bot.buildPath("getFile")
-	body, error := postPetition(url, struct {
+	file, err := Call[*File](bot.Context(), bot, "getFile", struct {
 		ID string `json:"file_id"`
-	}{id}, nil)
-
-	if error != nil {
-		errc := 500
-		err := "Some error happened while sending the message"
-		return ResultWithGetFile{ResultBase{false, &errc, &err}, nil}
-	}
-	var result ResultWithGetFile
-	json.Unmarshal([]byte(body), &result)
-	return result
+	}{id})
+	return ResultWithGetFile{resultBase(err), file}
 }
 
 func (bot TgBot) DownloadFilePathReader(path string) (io.ReadCloser, error) {
 	url := bot.buildFilePath(path)
-	resp, _, errq := gorequest.New().
-		DisableKeepAlives(true).
-		CloseRequest(true).
-		Get(url).
-		End()
-	if errq != nil {
-		if len(errq) > 0 {
-			return nil, errq[0]
-		} else {
-			return nil, errors.New("Error in GET petition")
-		}
+	resp, err := bot.client().Get(url)
+	if err != nil {
+		return nil, bot.redactError(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New("Error in GET petition")
 	}
 
 	return resp.Body, nil
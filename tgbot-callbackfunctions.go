@@ -8,7 +8,7 @@ func (bot *TgBot) CommandFn(path string, f func(TgBot, Message, []string, map[st
 	path = bot.addUsernameCommand(path)
 	r := regexp.MustCompile(path)
 
-	bot.addToConditionalFuncs(TextConditionalCall{RegexCommand{r, f}})
+	bot.addToConditionalFuncs(CommandConditionalCall{TextConditionalCall{RegexCommand{r, f}}})
 	return bot
 }
 
@@ -19,7 +19,7 @@ func (bot *TgBot) SimpleCommandFn(path string, f func(TgBot, Message, string) *s
 	r := regexp.MustCompile(path)
 	newf := SimpleCommandFuncStruct{f}
 
-	bot.addToConditionalFuncs(TextConditionalCall{RegexCommand{r, newf.CallSimpleCommandFunc}})
+	bot.addToConditionalFuncs(CommandConditionalCall{TextConditionalCall{RegexCommand{r, newf.CallSimpleCommandFunc}}})
 	return bot
 }
 
@@ -33,7 +33,7 @@ func (bot *TgBot) MultiCommandFn(paths []string, f func(TgBot, Message, []string
 		rc = append(rc, r)
 	}
 
-	bot.addToConditionalFuncs(TextConditionalCall{MultiRegexCommand{rc, f}})
+	bot.addToConditionalFuncs(CommandConditionalCall{TextConditionalCall{MultiRegexCommand{rc, f}}})
 	return bot
 }
 
@@ -198,7 +198,7 @@ func (bot *TgBot) CancelChainCommand(path string, f func(TgBot, Message, string)
 		r := regexp.MustCompile(path)
 		newf := SimpleCommandFuncStruct{f}
 		bot.ChainConditionals[len(bot.ChainConditionals)-1].
-			SetCancelCond(TextConditionalCall{RegexCommand{r, newf.CallSimpleCommandFunc}})
+			SetCancelCond(CommandConditionalCall{TextConditionalCall{RegexCommand{r, newf.CallSimpleCommandFunc}}})
 	}
 	return bot
 }
@@ -0,0 +1,251 @@
+package tgbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	certValidFor      = 365 * 24 * time.Hour
+	certRenewBefore   = 30 * 24 * time.Hour
+	certCheckInterval = time.Hour
+)
+
+// GenerateSelfSignedCert creates a self-signed certificate and its RSA private key for the host (a domain or an IP), PEM encoded.
+func GenerateSelfSignedCert(host string, validFor time.Duration) (certPEM []byte, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// SelfSignedCert keeps a self-signed certificate for the webhook, generating it again before it expires.
+type SelfSignedCert struct {
+	Host        string
+	CacheDir    string        // If not empty, the certificate and key are stored there and reused
+	ValidFor    time.Duration // Default one year
+	RenewBefore time.Duration // Default 30 days
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certPEM  []byte
+	notAfter time.Time
+}
+
+// NewSelfSignedCert creates a self-signed certificate manager for the host, stored in cacheDir if it's not empty.
+func NewSelfSignedCert(host string, cacheDir string) *SelfSignedCert {
+	return &SelfSignedCert{Host: host, CacheDir: cacheDir}
+}
+
+// Load reads the certificate from the cache, or generates a new one if there is none or it's about to expire.
+func (sc *SelfSignedCert) Load() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.CacheDir != "" {
+		certPEM, cerr := ioutil.ReadFile(sc.cachePath(".crt"))
+		keyPEM, kerr := ioutil.ReadFile(sc.cachePath(".key"))
+		if cerr == nil && kerr == nil {
+			if err := sc.use(certPEM, keyPEM); err == nil && !sc.needsRenewal() {
+				return nil
+			}
+		}
+	}
+	return sc.generate()
+}
+
+// Renew generates a new certificate and uses it right away.
+func (sc *SelfSignedCert) Renew() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.generate()
+}
+
+// NeedsRenewal returns true if there is no certificate or it expires in less than RenewBefore.
+func (sc *SelfSignedCert) NeedsRenewal() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.needsRenewal()
+}
+
+// CertPEM returns the public certificate, the one that is uploaded to Telegram.
+func (sc *SelfSignedCert) CertPEM() []byte {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.certPEM
+}
+
+// GetCertificate returns the current certificate, use it in tls.Config so the renewals are served without restarting.
+func (sc *SelfSignedCert) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.cert == nil {
+		return nil, errors.New("The self-signed certificate is not loaded")
+	}
+	return sc.cert, nil
+}
+
+// TLSConfig returns a tls.Config that serves the current certificate.
+func (sc *SelfSignedCert) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: sc.GetCertificate}
+}
+
+func (sc *SelfSignedCert) needsRenewal() bool {
+	renew := sc.RenewBefore
+	if renew == 0 {
+		renew = certRenewBefore
+	}
+	return sc.cert == nil || time.Now().Add(renew).After(sc.notAfter)
+}
+
+func (sc *SelfSignedCert) generate() error {
+	certPEM, keyPEM, err := sc.newPair()
+	if err != nil {
+		return err
+	}
+	return sc.store(certPEM, keyPEM)
+}
+
+// newPair generates a certificate without using it.
+func (sc *SelfSignedCert) newPair() ([]byte, []byte, error) {
+	validFor := sc.ValidFor
+	if validFor == 0 {
+		validFor = certValidFor
+	}
+	return GenerateSelfSignedCert(sc.Host, validFor)
+}
+
+// store uses the certificate and saves it in the cache.
+func (sc *SelfSignedCert) store(certPEM []byte, keyPEM []byte) error {
+	if err := sc.use(certPEM, keyPEM); err != nil {
+		return err
+	}
+	if sc.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(sc.CacheDir, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(sc.cachePath(".key"), keyPEM, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(sc.cachePath(".crt"), certPEM, 0644)
+}
+
+func (sc *SelfSignedCert) use(certPEM []byte, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+	sc.cert = &cert
+	sc.certPEM = certPEM
+	sc.notAfter = leaf.NotAfter
+	return nil
+}
+
+func (sc *SelfSignedCert) cachePath(ext string) string {
+	return filepath.Join(sc.CacheDir, sc.Host+ext)
+}
+
+// SetWebhookWithSelfSignedCert calls setWebhook uploading the public certificate.
+func (bot *TgBot) SetWebhookWithSelfSignedCert(q SetWebhookQuery, sc *SelfSignedCert) ResultSetWebhook {
+	q.Certificate = ReaderSender{bytes.NewReader(sc.CertPEM()), sc.Host + ".pem"}
+	return bot.SetWebhookWithOptions(q)
+}
+
+// ListenAndServeSelfSigned loads the certificate, sets the webhook with it and serves HTTPS with its key until the context is cancelled.
+// The certificate is renewed before it expires, the new one is only served after uploading it (the upload is retried every hour if it fails).
+func (bot *TgBot) ListenAndServeSelfSigned(ctx context.Context, addr string, q SetWebhookQuery, sc *SelfSignedCert, handler http.Handler) error {
+	if err := sc.Load(); err != nil {
+		return err
+	}
+	if res := bot.SetWebhookWithSelfSignedCert(q, sc); !res.Ok {
+		return errors.New(res.Description)
+	}
+
+	go bot.rotateSelfSignedCert(ctx, q, sc)
+
+	srv := &http.Server{Addr: addr, Handler: handler, TLSConfig: sc.TLSConfig()}
+	return serveUntilDone(ctx, srv, func() error {
+		return srv.ListenAndServeTLS("", "")
+	})
+}
+
+func (bot *TgBot) rotateSelfSignedCert(ctx context.Context, q SetWebhookQuery, sc *SelfSignedCert) {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !sc.NeedsRenewal() {
+			continue
+		}
+		certPEM, keyPEM, err := sc.newPair()
+		if err != nil {
+			bot.log().Error("Error renewing the certificate", "host", sc.Host, "error", err)
+			continue
+		}
+		// Telegram keeps using the old certificate until the new one is uploaded, so until then the old one is served
+		q.Certificate = ReaderSender{bytes.NewReader(certPEM), sc.Host + ".pem"}
+		if res := bot.SetWebhookWithOptions(q); !res.Ok {
+			bot.log().Error("Error uploading the renewed certificate", "method", "setWebhook", "error_code", errorCode(res.ErrorCode), "description", res.Description)
+			continue
+		}
+		sc.mu.Lock()
+		err = sc.store(certPEM, keyPEM)
+		sc.mu.Unlock()
+		if err != nil {
+			bot.log().Error("Error storing the renewed certificate", "host", sc.Host, "error", err)
+		}
+	}
+}
@@ -1,23 +1,69 @@
 package tgbot
 
-import "github.com/oleiade/reflections"
+import (
+	"reflect"
+	"time"
+)
 
 // DefaultOptionsBot represents the options that the bot will try to apply automatically
 type DefaultOptionsBot struct {
 	DisableWebURL              *bool
 	Selective                  *bool
 	OneTimeKeyboard            *bool
+	ParseMode                  *ParseModeT
+	DisableNotification        *bool
+	ProtectContent             *bool
+	AllowSendingWithoutReply   *bool
 	CleanInitialUsername       bool
 	AllowWithoutSlashInMention bool
 	LowerText                  bool
 	RecoverPanic               bool
+	PanicChat                  int
+	ListenerBuffer             int
+	Workers                    int
+	OverflowPolicy             OverflowPolicy
+	AtLeastOnce                bool
+	WebhookReplyTimeout        time.Duration
 }
 
+// SetRecoverPanic sets if the panics in the handlers are recovered and reported (the default), or crash the program.
 func (bot *TgBot) SetRecoverPanic(b bool) *TgBot {
 	bot.DefaultOptions.RecoverPanic = b
 	return bot
 }
 
+// SetListenerBuffer sets the buffer size of the channel created by GetMessageChannel, call it before starting the bot.
+func (bot *TgBot) SetListenerBuffer(size int) *TgBot {
+	bot.DefaultOptions.ListenerBuffer = size
+	return bot
+}
+
+// SetWorkers sets how many updates are processed at the same time by the default listener, call it before starting the bot.
+func (bot *TgBot) SetWorkers(n int) *TgBot {
+	bot.DefaultOptions.Workers = n
+	return bot
+}
+
+// SetOverflowPolicy sets what happens with new updates when the MainListener is full.
+func (bot *TgBot) SetOverflowPolicy(op OverflowPolicy) *TgBot {
+	bot.DefaultOptions.OverflowPolicy = op
+	return bot
+}
+
+// SetAtLeastOnce makes the bot acknowledge the updates to Telegram only after the handlers finish,
+// if the process dies in the middle the updates will be received again.
+func (bot *TgBot) SetAtLeastOnce(b bool) *TgBot {
+	bot.DefaultOptions.AtLeastOnce = b
+	return bot
+}
+
+// SetWebhookSecret sets the secret token that the webhook handler expects in the X-Telegram-Bot-Api-Secret-Token header.
+// SetWebhookWithOptions sets it for you, use this if the webhook was set somewhere else.
+func (bot *TgBot) SetWebhookSecret(secret string) *TgBot {
+	bot.WebhookSecret = secret
+	return bot
+}
+
 func (bot *TgBot) SetLowerText(b bool) *TgBot {
 	bot.DefaultOptions.LowerText = b
 	return bot
@@ -41,6 +87,30 @@ func (bot *TgBot) DefaultOneTimeKeyboard(b bool) *TgBot {
 	return bot
 }
 
+// DefaultParseMode sets the parse mode of the texts sent without parse mode nor entities.
+func (bot *TgBot) DefaultParseMode(pm ParseModeT) *TgBot {
+	bot.DefaultOptions.ParseMode = &pm
+	return bot
+}
+
+// DefaultDisableNotification sends all the messages silently, unless they say otherwise.
+func (bot *TgBot) DefaultDisableNotification(b bool) *TgBot {
+	bot.DefaultOptions.DisableNotification = &b
+	return bot
+}
+
+// DefaultProtectContent protects all the messages from being forwarded and saved, unless they say otherwise.
+func (bot *TgBot) DefaultProtectContent(b bool) *TgBot {
+	bot.DefaultOptions.ProtectContent = &b
+	return bot
+}
+
+// DefaultAllowSendingWithoutReply sends the replies even if the message they answer was deleted, unless they say otherwise.
+func (bot *TgBot) DefaultAllowSendingWithoutReply(b bool) *TgBot {
+	bot.DefaultOptions.AllowSendingWithoutReply = &b
+	return bot
+}
+
 // DefaultCleanInitialUsername ...
 func (bot *TgBot) DefaultCleanInitialUsername(b bool) *TgBot {
 	bot.DefaultOptions.CleanInitialUsername = b
@@ -53,79 +123,120 @@ func (bot *TgBot) DefaultAllowWithoutSlashInMention(b bool) *TgBot {
 	return bot
 }
 
-func hookDisableWebpage(payload interface{}, nv *bool) {
-	if nv != nil {
-		has, _ := reflections.HasField(payload, "DisableWebPagePreview")
-		if has {
-			value, _ := reflections.GetField(payload, "DisableWebPagePreview")
-			bvalue := value.(*bool)
-			if bvalue == nil {
-				reflections.SetField(payload, "DisableWebPagePreview", nv)
-			}
-		}
-	}
+// defaultsApplier is implemented by the queries that accept some of the default options,
+// the send queries get it from the SendOptions they embed.
+type defaultsApplier interface {
+	applyDefaults(opts DefaultOptionsBot)
 }
 
-func hookReplyToMessageID(payload interface{}, nv *bool) {
-	if nv != nil {
-		has, _ := reflections.HasField(payload, "ReplyToMessageID")
-		if has {
-			value, _ := reflections.GetField(payload, "ReplyToMessageID")
-			bvalue := value.(*int)
-			if bvalue == nil {
-				reflections.SetField(payload, "ReplyToMessageID", nv)
-			}
+// withDefaults returns the payload with the options that it doesn't set filled with the defaults of the bot.
+// The payloads given by value are copied to a pointer, so the defaults apply to them too.
+func (bot TgBot) withDefaults(payload interface{}) interface{} {
+	if fp, ok := payload.(fileParams); ok {
+		fp.params = bot.withDefaults(fp.params)
+		return fp
+	}
+	if payload == nil {
+		return nil
+	}
+	if v := reflect.ValueOf(payload); v.Kind() != reflect.Ptr {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		if _, ok := p.Interface().(defaultsApplier); ok {
+			payload = p.Interface()
 		}
 	}
+	if da, ok := payload.(defaultsApplier); ok {
+		da.applyDefaults(bot.DefaultOptions)
+	}
+	return payload
 }
 
-func hookSelective(payload interface{}, nv *bool) {
-	if nv != nil {
-		has, _ := reflections.HasField(payload, "Selective")
-		if has {
-			value, _ := reflections.GetField(payload, "Selective")
-			bvalue := value.(*bool)
-			if bvalue == nil {
-				reflections.SetField(payload, "Selective", nv)
-			}
-		}
+func (so *SendOptions) applyDefaults(opts DefaultOptionsBot) {
+	if so.DisableNotification == nil {
+		so.DisableNotification = opts.DisableNotification
+	}
+	if so.ProtectContent == nil {
+		so.ProtectContent = opts.ProtectContent
+	}
+	if so.AllowSendingWithoutReply == nil && so.ReplyToMessageID != nil {
+		so.AllowSendingWithoutReply = opts.AllowSendingWithoutReply
+	}
+	if so.ReplyMarkup != nil {
+		// A new markup, the one of the caller can be used in other messages
+		rm := markupWithDefaults(*so.ReplyMarkup, opts)
+		so.ReplyMarkup = &rm
 	}
 }
 
-func hookOneTimeKeyboard(payload interface{}, nv *bool) {
-	if nv != nil {
-		has, _ := reflections.HasField(payload, "OneTimeKeyboard")
-		if has {
-			value, _ := reflections.GetField(payload, "OneTimeKeyboard")
-			bvalue := value.(*bool)
-			if bvalue == nil {
-				reflections.SetField(payload, "OneTimeKeyboard", nv)
-			}
-		}
+func (q *QuerySendMessage) applyDefaults(opts DefaultOptionsBot) {
+	if q.DisableWebPagePreview == nil {
+		q.DisableWebPagePreview = opts.DisableWebURL
+	}
+	// The entities already have the format
+	if q.ParseMode == nil && q.Entities == nil && opts.ParseMode != nil {
+		pm := opts.ParseMode.String()
+		q.ParseMode = &pm
 	}
+	q.SendOptions.applyDefaults(opts)
+}
+
+func (q *SendPhotoIDQuery) applyDefaults(opts DefaultOptionsBot) {
+	captionDefaults(q.Caption, &q.ParseMode, q.CaptionEntities, opts)
+	q.SendOptions.applyDefaults(opts)
+}
+
+func (q *SendPhotoPathQuery) applyDefaults(opts DefaultOptionsBot) {
+	captionDefaults(q.Caption, &q.ParseMode, q.CaptionEntities, opts)
+	q.SendOptions.applyDefaults(opts)
 }
 
-// HookPayload I hate reflection, sorry for that <3
-func hookPayload(payload interface{}, opts DefaultOptionsBot) {
-	hookDisableWebpage(payload, opts.DisableWebURL)
-	// HookReplyToMessageID(payload, opts.ReplyToMessageID)
+func (q *SendVideoIDQuery) applyDefaults(opts DefaultOptionsBot) {
+	captionDefaults(q.Caption, &q.ParseMode, q.CaptionEntities, opts)
+	q.SendOptions.applyDefaults(opts)
+}
 
-	has, _ := reflections.HasField(payload, "ReplyMarkup")
+func (q *SendVideoPathQuery) applyDefaults(opts DefaultOptionsBot) {
+	captionDefaults(q.Caption, &q.ParseMode, q.CaptionEntities, opts)
+	q.SendOptions.applyDefaults(opts)
+}
 
-	if has {
-		keyint, _ := reflections.GetField(payload, "ReplyMarkup")
+// captionDefaults sets the default parse mode of a caption without format.
+func captionDefaults(caption *string, parseMode **string, entities *[]MessageEntity, opts DefaultOptionsBot) {
+	if caption != nil && *parseMode == nil && entities == nil && opts.ParseMode != nil {
+		pm := opts.ParseMode.String()
+		*parseMode = &pm
+	}
+}
 
-		switch val := keyint.(type) {
-		case *ForceReply, *ReplyKeyboardHide:
-			if val != nil {
-				hookSelective(val, opts.Selective)
-			}
-		case *ReplyKeyboardMarkup:
-			if val != nil {
-				hookOneTimeKeyboard(val, opts.OneTimeKeyboard)
-				hookSelective(val, opts.Selective)
-			}
-		default:
+// markupWithDefaults returns a copy of the keyboard with the selective and one time defaults,
+// they are plain booleans in the keyboards, so the defaults can only turn them on.
+func markupWithDefaults(rm ReplyMarkupInt, opts DefaultOptionsBot) ReplyMarkupInt {
+	selective := opts.Selective != nil && *opts.Selective
+	onetime := opts.OneTimeKeyboard != nil && *opts.OneTimeKeyboard
+	switch m := rm.(type) {
+	case ReplyKeyboardMarkup:
+		m.Selective = m.Selective || selective
+		m.OneTimeKeyboard = m.OneTimeKeyboard || onetime
+		return m
+	case *ReplyKeyboardMarkup:
+		if m != nil {
+			return markupWithDefaults(*m, opts)
+		}
+	case ReplyKeyboardHide:
+		m.Selective = m.Selective || selective
+		return m
+	case *ReplyKeyboardHide:
+		if m != nil {
+			return markupWithDefaults(*m, opts)
+		}
+	case ForceReply:
+		m.Selective = m.Selective || selective
+		return m
+	case *ForceReply:
+		if m != nil {
+			return markupWithDefaults(*m, opts)
 		}
 	}
+	return rm
 }
@@ -0,0 +1,89 @@
+package tgbot
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Extract returns the part of the text covered by the entity, the offsets are counted in UTF-16 like Telegram does.
+func (e MessageEntity) Extract(text string) string {
+	units := utf16.Encode([]rune(text))
+	start, end := e.Offset, e.Offset+e.Length
+	if start < 0 || end > len(units) || start > end {
+		return ""
+	}
+	return string(utf16.Decode(units[start:end]))
+}
+
+// TextEntities returns the entities of the text, or of the caption if it's a media message, with the text they refer to.
+func (msg Message) TextEntities() (string, []MessageEntity) {
+	if msg.Text != nil {
+		if msg.Entities != nil {
+			return *msg.Text, *msg.Entities
+		}
+		return *msg.Text, nil
+	}
+	if msg.Caption != nil && msg.CaptionEntities != nil {
+		return *msg.Caption, *msg.CaptionEntities
+	}
+	return "", nil
+}
+
+// EntityTexts returns the texts of the entities of the type, like "mention", "hashtag", "url" or "bot_command".
+func (msg Message) EntityTexts(entityType string) []string {
+	text, entities := msg.TextEntities()
+	texts := []string{}
+	for _, e := range entities {
+		if e.Type == entityType {
+			texts = append(texts, e.Extract(text))
+		}
+	}
+	return texts
+}
+
+// Command returns the command at the start of the message, without the slash, and the bot it's sent to if it has one (/command@botname).
+func (msg Message) Command() (command string, botname string, ok bool) {
+	text, entities := msg.TextEntities()
+	for _, e := range entities {
+		if e.Type == "bot_command" && e.Offset == 0 {
+			command = strings.TrimPrefix(e.Extract(text), "/")
+			if i := strings.Index(command, "@"); i >= 0 {
+				command, botname = command[:i], command[i+1:]
+			}
+			return command, botname, true
+		}
+	}
+	return "", "", false
+}
+
+// commandForMe returns false if the message has entities and its command is not for this bot.
+// Without entities (old clients, or hand made messages) the regular expression decides.
+func (bot TgBot) commandForMe(msg Message) bool {
+	if msg.Text == nil || msg.Entities == nil {
+		return true
+	}
+	_, botname, ok := msg.Command()
+	if !ok {
+		return false
+	}
+	return botname == "" || strings.EqualFold(botname, bot.Username)
+}
+
+// shiftEntities moves the entities after removing removed UTF-16 units from the start of the text,
+// the entities in the removed part are dropped.
+func shiftEntities(entities []MessageEntity, removed int) []MessageEntity {
+	shifted := []MessageEntity{}
+	for _, e := range entities {
+		if e.Offset < removed {
+			continue
+		}
+		e.Offset -= removed
+		shifted = append(shifted, e)
+	}
+	return shifted
+}
+
+// UTF16Len returns the length of the text in UTF-16 code units, the unit of the entity offsets and the Telegram limits.
+func UTF16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
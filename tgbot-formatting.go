@@ -0,0 +1,350 @@
+package tgbot
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// FormattedText is a text with its format, ready to be sent. The format goes in the parse mode, or in the entities when the parse mode is nil.
+type FormattedText interface {
+	Text() string
+	ParseMode() *ParseModeT
+	Entities() *[]MessageEntity
+}
+
+// TextBuilder composes a formatted text escaping every part for its format, so the user input never breaks the message.
+// The parts are added in order, create one with NewMarkdownV2Builder, NewHTMLBuilder or NewEntitiesBuilder.
+type TextBuilder struct {
+	mode     *ParseModeT
+	text     strings.Builder
+	length   int // In UTF-16 code units, for the entities
+	entities []MessageEntity
+}
+
+// NewMarkdownV2Builder starts a text in MarkdownV2.
+func NewMarkdownV2Builder() *TextBuilder {
+	mode := MarkdownV2
+	return &TextBuilder{mode: &mode}
+}
+
+// NewHTMLBuilder starts a text in HTML.
+func NewHTMLBuilder() *TextBuilder {
+	mode := Html
+	return &TextBuilder{mode: &mode}
+}
+
+// NewEntitiesBuilder starts a plain text with its entities, without parse mode, nothing needs to be escaped.
+func NewEntitiesBuilder() *TextBuilder {
+	return &TextBuilder{}
+}
+
+// Plain adds text without format.
+func (tb *TextBuilder) Plain(s string) *TextBuilder {
+	return tb.add(MessageEntity{}, s)
+}
+
+// Plainf adds text without format, like fmt.Sprintf, the result is escaped.
+func (tb *TextBuilder) Plainf(format string, a ...interface{}) *TextBuilder {
+	return tb.Plain(fmt.Sprintf(format, a...))
+}
+
+// Line adds a new line.
+func (tb *TextBuilder) Line() *TextBuilder {
+	return tb.Plain("\n")
+}
+
+// Bold adds bold text.
+func (tb *TextBuilder) Bold(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "bold"}, s)
+}
+
+// Italic adds italic text.
+func (tb *TextBuilder) Italic(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "italic"}, s)
+}
+
+// Underline adds underlined text.
+func (tb *TextBuilder) Underline(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "underline"}, s)
+}
+
+// Strikethrough adds strikethrough text.
+func (tb *TextBuilder) Strikethrough(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "strikethrough"}, s)
+}
+
+// Spoiler adds text hidden until it's clicked.
+func (tb *TextBuilder) Spoiler(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "spoiler"}, s)
+}
+
+// Code adds inline monospace text.
+func (tb *TextBuilder) Code(s string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "code"}, s)
+}
+
+// Pre adds a block of code, the language can be empty.
+func (tb *TextBuilder) Pre(s string, language string) *TextBuilder {
+	e := MessageEntity{Type: "pre"}
+	if language != "" {
+		e.Language = &language
+	}
+	return tb.add(e, s)
+}
+
+// Link adds the text linking to the URL.
+func (tb *TextBuilder) Link(s string, url string) *TextBuilder {
+	return tb.add(MessageEntity{Type: "text_link", URL: &url}, s)
+}
+
+// Mention adds the text mentioning the user, it works with the users without username.
+func (tb *TextBuilder) Mention(s string, user User) *TextBuilder {
+	return tb.add(MessageEntity{Type: "text_mention", User: &user}, s)
+}
+
+// Text returns the text built.
+func (tb *TextBuilder) Text() string {
+	if tb.mode == nil {
+		return tb.text.String()
+	}
+	return renderEntities(tb.text.String(), tb.entities, *tb.mode)
+}
+
+// ParseMode returns the parse mode of the text, nil with the entities builder.
+func (tb *TextBuilder) ParseMode() *ParseModeT {
+	return tb.mode
+}
+
+// Entities returns the entities of the text, nil with the parse mode builders.
+func (tb *TextBuilder) Entities() *[]MessageEntity {
+	if tb.mode != nil {
+		return nil
+	}
+	entities := append([]MessageEntity{}, tb.entities...)
+	return &entities
+}
+
+// add writes the part, the entity without type is plain text.
+// The builder always keeps the plain text and its entities, the parse modes render them when the text is asked.
+func (tb *TextBuilder) add(e MessageEntity, s string) *TextBuilder {
+	if e.Type != "" && s != "" {
+		e.Offset, e.Length = tb.length, UTF16Len(s)
+		tb.entities = append(tb.entities, e)
+	}
+	tb.text.WriteString(s)
+	tb.length += UTF16Len(s)
+	return tb
+}
+
+// formattedText is a FormattedText already built, like the parts of a split text.
+type formattedText struct {
+	text     string
+	mode     *ParseModeT
+	entities *[]MessageEntity
+}
+
+func (ft formattedText) Text() string               { return ft.text }
+func (ft formattedText) ParseMode() *ParseModeT     { return ft.mode }
+func (ft formattedText) Entities() *[]MessageEntity { return ft.entities }
+
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+var markdownV2CodeReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+var markdownV2URLReplacer = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+var markdownReplacer = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+// EscapeMarkdownV2 escapes the text to be used in a MarkdownV2 message outside of the entities.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+// EscapeHTML escapes the text to be used in a HTML message.
+func EscapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+// renderEntities writes the text with its entities in the parse mode, the entities can be nested.
+func renderEntities(text string, entities []MessageEntity, mode ParseModeT) string {
+	sorted := append([]MessageEntity{}, entities...)
+	sortEntities(sorted)
+	units := utf16.Encode([]rune(text))
+	return renderRange(units, 0, len(units), sorted, mode, "")
+}
+
+// renderRange renders the units between start and end, the entities are sorted and inside the range.
+// parent is the type of the entity that contains the range, the escaping depends on it.
+func renderRange(units []uint16, start int, end int, entities []MessageEntity, mode ParseModeT, parent string) string {
+	var sb strings.Builder
+	pos := start
+	for i := 0; i < len(entities); {
+		e := entities[i]
+		j := i + 1
+		for j < len(entities) && entities[j].Offset < e.Offset+e.Length {
+			j++
+		}
+		estart, eend := e.Offset, e.Offset+e.Length
+		if estart < pos {
+			estart = pos
+		}
+		if eend > end {
+			eend = end
+		}
+		if eend > estart {
+			sb.WriteString(escapeFor(mode, decodeUnits(units[pos:estart]), parent))
+			inner := renderRange(units, estart, eend, entities[i+1:j], mode, e.Type)
+			sb.WriteString(wrapFor(mode, e, inner))
+			pos = eend
+		}
+		i = j
+	}
+	sb.WriteString(escapeFor(mode, decodeUnits(units[pos:end]), parent))
+	return sb.String()
+}
+
+func decodeUnits(units []uint16) string {
+	return string(utf16.Decode(units))
+}
+
+func escapeFor(mode ParseModeT, s string, parent string) string {
+	switch mode {
+	case Html:
+		return EscapeHTML(s)
+	case MarkdownV2:
+		if parent == "code" || parent == "pre" {
+			return markdownV2CodeReplacer.Replace(s)
+		}
+		return EscapeMarkdownV2(s)
+	}
+	// Markdown can't escape inside the entities, markdownEntity closes them around the delimiters
+	if parent != "" {
+		return s
+	}
+	return markdownReplacer.Replace(s)
+}
+
+func wrapFor(mode ParseModeT, e MessageEntity, inner string) string {
+	switch mode {
+	case Html:
+		return htmlEntity(e, inner)
+	case MarkdownV2:
+		return markdownV2Entity(e, inner)
+	}
+	return markdownEntity(e, inner)
+}
+
+// markdownV2Entity wraps the text, already escaped, with the entity.
+func markdownV2Entity(e MessageEntity, s string) string {
+	switch e.Type {
+	case "bold":
+		return "*" + s + "*"
+	case "italic":
+		// The \r avoids the ambiguity of italic and underline together, Telegram ignores it
+		return "_" + s + "_\r"
+	case "underline":
+		return "__" + s + "__"
+	case "strikethrough":
+		return "~" + s + "~"
+	case "spoiler":
+		return "||" + s + "||"
+	case "code":
+		return "`" + s + "`"
+	case "pre":
+		return "```" + markdownV2CodeReplacer.Replace(entityLanguage(e)) + "\n" + s + "\n```"
+	case "text_link":
+		return "[" + s + "](" + markdownV2URLReplacer.Replace(*e.URL) + ")"
+	case "text_mention":
+		return fmt.Sprintf("[%s](tg://user?id=%d)", s, e.User.ID)
+	case "blockquote":
+		return ">" + strings.Replace(s, "\n", "\n>", -1)
+	}
+	return s
+}
+
+// htmlEntity wraps the text, already escaped, with the entity.
+func htmlEntity(e MessageEntity, s string) string {
+	switch e.Type {
+	case "bold":
+		return "<b>" + s + "</b>"
+	case "italic":
+		return "<i>" + s + "</i>"
+	case "underline":
+		return "<u>" + s + "</u>"
+	case "strikethrough":
+		return "<s>" + s + "</s>"
+	case "spoiler":
+		return "<tg-spoiler>" + s + "</tg-spoiler>"
+	case "code":
+		return "<code>" + s + "</code>"
+	case "pre":
+		if e.Language != nil {
+			return fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`, EscapeHTML(*e.Language), s)
+		}
+		return "<pre>" + s + "</pre>"
+	case "text_link":
+		return fmt.Sprintf(`<a href="%s">%s</a>`, EscapeHTML(*e.URL), s)
+	case "text_mention":
+		return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, e.User.ID, s)
+	case "blockquote":
+		return "<blockquote>" + s + "</blockquote>"
+	}
+	return s
+}
+
+// markdownEntity wraps the text with the entity, the old Markdown only knows some of them.
+func markdownEntity(e MessageEntity, s string) string {
+	switch e.Type {
+	case "bold":
+		return markdownWrap(s, "*", "*", "*")
+	case "italic":
+		return markdownWrap(s, "_", "_", "_")
+	case "code":
+		return markdownWrap(s, "`", "`", "`")
+	case "pre":
+		return markdownWrap(s, "`", "```"+entityLanguage(e)+"\n", "\n```")
+	case "text_link":
+		return markdownWrap(s, "]", "[", "]("+*e.URL+")")
+	case "text_mention":
+		return markdownWrap(s, "]", "[", fmt.Sprintf("](tg://user?id=%d)", e.User.ID))
+	}
+	return s
+}
+
+// markdownWrap wraps the text with open and close. Markdown can't escape the delimiter of the entity inside it,
+// so the entity is closed before the delimiter, that is written escaped, and opened again after it.
+func markdownWrap(s string, delim string, open string, close string) string {
+	var sb strings.Builder
+	for i, part := range strings.Split(s, delim) {
+		if i > 0 {
+			sb.WriteString(markdownReplacer.Replace(delim))
+		}
+		if part != "" {
+			sb.WriteString(open + part + close)
+		}
+	}
+	return sb.String()
+}
+
+func entityLanguage(e MessageEntity) string {
+	if e.Language == nil {
+		return ""
+	}
+	return *e.Language
+}
+
+// sortEntities sorts the entities by offset, the outer entity first.
+func sortEntities(entities []MessageEntity) {
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].Offset != entities[j].Offset {
+			return entities[i].Offset < entities[j].Offset
+		}
+		return entities[i].Length > entities[j].Length
+	})
+}
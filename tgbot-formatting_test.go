@@ -0,0 +1,86 @@
+package tgbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarkdownV2BuilderEscapes(t *testing.T) {
+	text := NewMarkdownV2Builder().
+		Plain("1+1=2. ").Bold("a*b").Plain(" ").Code("x`y\\z").Plain(" ").Link("site", "https://e.com/a_(b)").
+		Text()
+	want := "1\\+1\\=2\\. *a\\*b* `x\\`y\\\\z` [site](https://e.com/a_(b\\))"
+	if text != want {
+		t.Errorf("text = %q\nwant   %q", text, want)
+	}
+}
+
+func TestMarkdownV2PreLanguage(t *testing.T) {
+	text := NewMarkdownV2Builder().Pre("fmt.Println(`hi`)", "go`x").Text()
+	want := "```go\\`x\nfmt.Println(\\`hi\\`)\n```"
+	if text != want {
+		t.Errorf("text = %q\nwant   %q", text, want)
+	}
+}
+
+func TestHTMLBuilderEscapes(t *testing.T) {
+	text := NewHTMLBuilder().
+		Plain("a<b & c").Bold("<i>").Link("x", `https://e.com/?a=1&b="2"`).Pre("if a < b {}", "go").
+		Text()
+	want := `a&lt;b &amp; c<b>&lt;i&gt;</b><a href="https://e.com/?a=1&amp;b=&#34;2&#34;">x</a>` +
+		`<pre><code class="language-go">if a &lt; b {}</code></pre>`
+	if text != want {
+		t.Errorf("text = %q\nwant   %q", text, want)
+	}
+}
+
+func TestEntitiesBuilderCountsUTF16(t *testing.T) {
+	tb := NewEntitiesBuilder().Plain("😀 ").Bold("hi").Italic("é")
+	if tb.ParseMode() != nil || tb.Text() != "😀 hié" {
+		t.Fatalf("text = %q with parse mode %v", tb.Text(), tb.ParseMode())
+	}
+	want := []MessageEntity{{Type: "bold", Offset: 3, Length: 2}, {Type: "italic", Offset: 5, Length: 1}}
+	if got := *tb.Entities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("entities = %+v, want %+v", got, want)
+	}
+}
+
+var roundTripText = "2*2=4 snake_case [x] `go`"
+
+var roundTripEntities = []MessageEntity{
+	{Type: "bold", Offset: 0, Length: 5},
+	{Type: "italic", Offset: 6, Length: 10},
+	{Type: "code", Offset: 21, Length: 4},
+}
+
+// The texts rendered are read back with the same entities.
+func TestRenderRoundTrip(t *testing.T) {
+	for _, mode := range []ParseModeT{MarkdownV2, Html} {
+		rendered := renderEntities(roundTripText, roundTripEntities, mode)
+		text, entities := parseMarkup(rendered, mode)
+		if text != roundTripText || !reflect.DeepEqual(entities, roundTripEntities) {
+			t.Errorf("mode %v: %q read as %q with %+v", mode, rendered, text, entities)
+		}
+	}
+}
+
+// Markdown can't escape inside the entities, the delimiters are left out of them but the text is kept.
+func TestRenderMarkdownDelimiters(t *testing.T) {
+	rendered := renderEntities(roundTripText, roundTripEntities, Markdown)
+	want := "*2*\\**2=4* _snake_\\__case_ \\[x] \\``go`\\`"
+	if rendered != want {
+		t.Errorf("rendered = %q\nwant       %q", rendered, want)
+	}
+
+	text, entities := parseMarkup(rendered, Markdown)
+	wantEntities := []MessageEntity{
+		{Type: "bold", Offset: 0, Length: 1},
+		{Type: "bold", Offset: 2, Length: 3},
+		{Type: "italic", Offset: 6, Length: 5},
+		{Type: "italic", Offset: 12, Length: 4},
+		{Type: "code", Offset: 22, Length: 2},
+	}
+	if text != roundTripText || !reflect.DeepEqual(entities, wantEntities) {
+		t.Errorf("read as %q with %+v", text, entities)
+	}
+}
@@ -0,0 +1,130 @@
+package tgbot
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Logger is used by the bot to write its diagnostics, a *slog.Logger can be used directly.
+// The arguments are key-value pairs, like in log/slog.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NewLogger creates a text Logger that writes to w the messages with the level or above.
+func NewLogger(w io.Writer, level slog.Level) Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+var defaultLogger = NewLogger(os.Stderr, slog.LevelInfo)
+
+// SetLogger sets the logger of the bot, the token is removed from everything that is logged.
+func (bot *TgBot) SetLogger(l Logger) *TgBot {
+	bot.Logger = l
+	return bot
+}
+
+// SetLogLevel makes the bot log to stderr the messages with the level or above.
+func (bot *TgBot) SetLogLevel(level slog.Level) *TgBot {
+	bot.Logger = NewLogger(os.Stderr, level)
+	return bot
+}
+
+// log returns the bot logger, wrapped to redact the token.
+func (bot TgBot) log() Logger {
+	l := bot.Logger
+	if l == nil {
+		l = defaultLogger
+	}
+	return redactLogger{l, bot.Token}
+}
+
+// redactLogger removes the token from the message and the values that are written as text.
+type redactLogger struct {
+	inner Logger
+	token string
+}
+
+func (rl redactLogger) Debug(msg string, args ...interface{}) {
+	rl.inner.Debug(redactToken(msg, rl.token), rl.redactArgs(args)...)
+}
+
+func (rl redactLogger) Info(msg string, args ...interface{}) {
+	rl.inner.Info(redactToken(msg, rl.token), rl.redactArgs(args)...)
+}
+
+func (rl redactLogger) Warn(msg string, args ...interface{}) {
+	rl.inner.Warn(redactToken(msg, rl.token), rl.redactArgs(args)...)
+}
+
+func (rl redactLogger) Error(msg string, args ...interface{}) {
+	rl.inner.Error(redactToken(msg, rl.token), rl.redactArgs(args)...)
+}
+
+func (rl redactLogger) redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch val := arg.(type) {
+		case string:
+			redacted[i] = redactToken(val, rl.token)
+		case []byte:
+			redacted[i] = redactToken(string(val), rl.token)
+		case error:
+			redacted[i] = redactToken(val.Error(), rl.token)
+		case fmt.Stringer:
+			redacted[i] = redactToken(val.String(), rl.token)
+		case fmt.Formatter:
+			redacted[i] = redactToken(fmt.Sprintf("%v", val), rl.token)
+		default:
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+// errorCode returns the error code of a result to log it, 0 if it doesn't have one.
+func errorCode(code *int) int {
+	if code == nil {
+		return 0
+	}
+	return *code
+}
+
+// redactToken replaces the token, and the token without the colon used in the webhook path, with <token>.
+func redactToken(s string, token string) string {
+	if token == "" {
+		return s
+	}
+	s = strings.Replace(s, token, "<token>", -1)
+	if tokenpath, err := tokenPath(token); err == nil {
+		s = strings.Replace(s, tokenpath, "<token>", -1)
+	}
+	return s
+}
+
+// redactedError is an error with the token removed from its message, it unwraps to the original one.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError returns the error with the token removed from the message, errors.Is and errors.As still see the original error.
+func (bot TgBot) redactError(err error) error {
+	if err == nil || bot.Token == "" {
+		return err
+	}
+	msg := err.Error()
+	if redacted := redactToken(msg, bot.Token); redacted != msg {
+		return &redactedError{redacted, err}
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactedErrorsUnwrap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	bot := newBot("1:secret", srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := Call[User](ctx, *bot, "getMe", nil)
+	if err == nil {
+		t.Fatal("the call didn't fail")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("the error has the token: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the error is not a context.DeadlineExceeded: %v", err)
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Errorf("the error is not a *url.Error: %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives the measures of the bot, implement it to send them to your metrics system or use PrometheusMetrics.
+// The methods are called from many goroutines.
+type Metrics interface {
+	// APICall is called after every request to the API, errorCode is 0 if it went ok.
+	APICall(method string, latency time.Duration, errorCode int)
+	// UpdateReceived is called when the handlers start with an update.
+	UpdateReceived(updateType string)
+	// Handler is called when a handler returns or panics.
+	Handler(name string, latency time.Duration, panicked bool)
+	// QueueDepth is called with the MainListener length when it changes.
+	QueueDepth(depth int)
+	// Wait is called when something had to wait for a limiter, "queue" is the MainListener being full.
+	Wait(limiter string, d time.Duration)
+}
+
+// SetMetrics sets where the bot sends its measures.
+func (bot *TgBot) SetMetrics(m Metrics) *TgBot {
+	bot.Metrics = m
+	return bot
+}
+
+func (bot TgBot) metrics() Metrics {
+	if bot.Metrics == nil {
+		return nopMetrics{}
+	}
+	return bot.Metrics
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) APICall(string, time.Duration, int)  {}
+func (nopMetrics) UpdateReceived(string)               {}
+func (nopMetrics) Handler(string, time.Duration, bool) {}
+func (nopMetrics) QueueDepth(int)                      {}
+func (nopMetrics) Wait(string, time.Duration)          {}
+
+// apiErrorCode returns the error code of an answer of the API, 0 if it went ok and 500 if there is no answer.
+func apiErrorCode(body string, err error) int {
+	if err != nil {
+		return 500
+	}
+	var res ResultBase
+	if json.Unmarshal([]byte(body), &res) != nil {
+		return 500
+	}
+	if !res.Ok {
+		if res.ErrorCode != nil {
+			return *res.ErrorCode
+		}
+		return 500
+	}
+	return 0
+}
+
+// handlerName is the label of a handler, its type and the pattern for the text and command handlers.
+func handlerName(c ConditionCallStructure) string {
+	if wrc, ok := c.(webhookReplyCall); ok {
+		c = wrc.inner
+	}
+	name := fmt.Sprintf("%T", c)
+	name = strings.TrimPrefix(name, "*")
+	name = strings.TrimPrefix(name, "tgbot.")
+
+	var internal CommandStructure
+	switch h := c.(type) {
+	case CommandConditionalCall:
+		internal = h.internal
+	case TextConditionalCall:
+		internal = h.internal
+	}
+	switch cmd := internal.(type) {
+	case RegexCommand:
+		name += " " + cmd.Regex.String()
+	case MultiRegexCommand:
+		patterns := make([]string, len(cmd.Regex))
+		for i, r := range cmd.Regex {
+			patterns[i] = r.String()
+		}
+		name += " " + strings.Join(patterns, " | ")
+	}
+	return name
+}
+
+// Buckets of the latency histograms, in seconds.
+var metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(metricsBuckets))
+	}
+	s := d.Seconds()
+	for i, b := range metricsBuckets {
+		if s <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+// PrometheusMetrics keeps the measures in memory and serves them in the Prometheus text format, mount it in your server (for example in /metrics).
+// To share one between many bots give each bot its ForBot, the measures of each one get its bot label.
+type PrometheusMetrics struct {
+	mu        sync.Mutex
+	calls     map[[3]string]uint64 // bot, method, error code
+	callTimes map[[2]string]*histogram
+	updates   map[[2]string]uint64
+	handlers  map[[2]string]*histogram
+	panics    map[[2]string]uint64
+	depth     map[string]int
+	waits     map[[2]string]*histogram
+}
+
+// NewPrometheusMetrics creates an empty PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		calls:     map[[3]string]uint64{},
+		callTimes: map[[2]string]*histogram{},
+		updates:   map[[2]string]uint64{},
+		handlers:  map[[2]string]*histogram{},
+		panics:    map[[2]string]uint64{},
+		depth:     map[string]int{},
+		waits:     map[[2]string]*histogram{},
+	}
+}
+
+// ForBot returns the Metrics of one bot, its measures have the bot label with the name.
+func (pm *PrometheusMetrics) ForBot(name string) Metrics {
+	return botMetrics{pm, name}
+}
+
+// APICall ...
+func (pm *PrometheusMetrics) APICall(method string, latency time.Duration, errorCode int) {
+	botMetrics{pm, ""}.APICall(method, latency, errorCode)
+}
+
+// UpdateReceived ...
+func (pm *PrometheusMetrics) UpdateReceived(updateType string) {
+	botMetrics{pm, ""}.UpdateReceived(updateType)
+}
+
+// Handler ...
+func (pm *PrometheusMetrics) Handler(name string, latency time.Duration, panicked bool) {
+	botMetrics{pm, ""}.Handler(name, latency, panicked)
+}
+
+// QueueDepth ...
+func (pm *PrometheusMetrics) QueueDepth(depth int) {
+	botMetrics{pm, ""}.QueueDepth(depth)
+}
+
+// Wait ...
+func (pm *PrometheusMetrics) Wait(limiter string, d time.Duration) {
+	botMetrics{pm, ""}.Wait(limiter, d)
+}
+
+// botMetrics are the measures of a bot in a PrometheusMetrics, the bot is empty if it's not shared.
+type botMetrics struct {
+	pm  *PrometheusMetrics
+	bot string
+}
+
+func (bm botMetrics) APICall(method string, latency time.Duration, errorCode int) {
+	bm.pm.mu.Lock()
+	defer bm.pm.mu.Unlock()
+	bm.pm.calls[[3]string{bm.bot, method, strconv.Itoa(errorCode)}]++
+	observeIn(bm.pm.callTimes, [2]string{bm.bot, method}, latency)
+}
+
+func (bm botMetrics) UpdateReceived(updateType string) {
+	bm.pm.mu.Lock()
+	defer bm.pm.mu.Unlock()
+	bm.pm.updates[[2]string{bm.bot, updateType}]++
+}
+
+func (bm botMetrics) Handler(name string, latency time.Duration, panicked bool) {
+	bm.pm.mu.Lock()
+	defer bm.pm.mu.Unlock()
+	observeIn(bm.pm.handlers, [2]string{bm.bot, name}, latency)
+	if panicked {
+		bm.pm.panics[[2]string{bm.bot, name}]++
+	}
+}
+
+func (bm botMetrics) QueueDepth(depth int) {
+	bm.pm.mu.Lock()
+	defer bm.pm.mu.Unlock()
+	bm.pm.depth[bm.bot] = depth
+}
+
+func (bm botMetrics) Wait(limiter string, d time.Duration) {
+	bm.pm.mu.Lock()
+	defer bm.pm.mu.Unlock()
+	observeIn(bm.pm.waits, [2]string{bm.bot, limiter}, d)
+}
+
+func observeIn(hs map[[2]string]*histogram, key [2]string, d time.Duration) {
+	h, ok := hs[key]
+	if !ok {
+		h = &histogram{}
+		hs[key] = h
+	}
+	h.observe(d)
+}
+
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	pm.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP tgbot_api_calls_total Requests to the Telegram API by method and error code (0 is ok).\n")
+	b.WriteString("# TYPE tgbot_api_calls_total counter\n")
+	calls := make([][3]string, 0, len(pm.calls))
+	for k := range pm.calls {
+		calls = append(calls, k)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		for n := range calls[i] {
+			if calls[i][n] != calls[j][n] {
+				return calls[i][n] < calls[j][n]
+			}
+		}
+		return false
+	})
+	for _, k := range calls {
+		fmt.Fprintf(&b, "tgbot_api_calls_total{%s} %d\n", labels(k[0], "method", k[1], "error_code", k[2]), pm.calls[k])
+	}
+
+	writeHistograms(&b, "tgbot_api_call_duration_seconds", "Latency of the requests to the Telegram API.", "method", pm.callTimes)
+
+	b.WriteString("# HELP tgbot_updates_received_total Updates given to the handlers by type.\n")
+	b.WriteString("# TYPE tgbot_updates_received_total counter\n")
+	for _, k := range sortedKeys(pm.updates) {
+		fmt.Fprintf(&b, "tgbot_updates_received_total{%s} %d\n", labels(k[0], "type", k[1]), pm.updates[k])
+	}
+
+	writeHistograms(&b, "tgbot_handler_duration_seconds", "Time spent in the handlers.", "handler", pm.handlers)
+
+	b.WriteString("# HELP tgbot_handler_panics_total Panics in the handlers.\n")
+	b.WriteString("# TYPE tgbot_handler_panics_total counter\n")
+	for _, k := range sortedKeys(pm.panics) {
+		fmt.Fprintf(&b, "tgbot_handler_panics_total{%s} %d\n", labels(k[0], "handler", k[1]), pm.panics[k])
+	}
+
+	b.WriteString("# HELP tgbot_queue_depth Updates waiting in the MainListener.\n")
+	b.WriteString("# TYPE tgbot_queue_depth gauge\n")
+	bots := make([]string, 0, len(pm.depth))
+	for bot := range pm.depth {
+		bots = append(bots, bot)
+	}
+	sort.Strings(bots)
+	for _, bot := range bots {
+		if bot == "" {
+			fmt.Fprintf(&b, "tgbot_queue_depth %d\n", pm.depth[bot])
+		} else {
+			fmt.Fprintf(&b, "tgbot_queue_depth{%s} %d\n", labels(bot), pm.depth[bot])
+		}
+	}
+
+	writeHistograms(&b, "tgbot_wait_seconds", "Time spent waiting for a limiter.", "limiter", pm.waits)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// labels writes the labels of a measure, the bot label only if the metrics are shared.
+func labels(bot string, kv ...string) string {
+	parts := []string{}
+	if bot != "" {
+		parts = append(parts, fmt.Sprintf("bot=%q", bot))
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", kv[i], kv[i+1]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func writeHistograms(b *strings.Builder, name string, help string, label string, hs map[[2]string]*histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	keys := make([][2]string, 0, len(hs))
+	for k := range hs {
+		keys = append(keys, k)
+	}
+	sortPairs(keys)
+	for _, k := range keys {
+		h := hs[k]
+		l := labels(k[0], label, k[1])
+		for i, le := range metricsBuckets {
+			fmt.Fprintf(b, "%s_bucket{%s,le=%q} %d\n", name, l, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, h.count)
+		fmt.Fprintf(b, "%s_sum{%s} %g\n", name, l, h.sum)
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, l, h.count)
+	}
+}
+
+func sortedKeys(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortPairs(keys)
+	return keys
+}
+
+func sortPairs(keys [][2]string) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+}
@@ -1,280 +1,122 @@
 package tgbot
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"image"
-	"image/gif"
-	"image/jpeg"
-	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-
-	"github.com/oleiade/reflections"
+	"strings"
 )
 
-func (bot TgBot) sendGenericQuery(path string, ignore string, file string, payload interface{}) ResultWithMessage {
-	url := bot.buildPath(path)
-	switch val := payload.(type) {
-	//WebHook
-	case SetWebhookQuery:
-		return bot.genericSendPostData(url, val)
-	case SetWebhookCertQuery:
-		return bot.sendConvertingFile(url, ignore, file, val)
-	// ID
-	case SendPhotoIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-	case SendAudioIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-	case SendVoiceIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-	case SendDocumentIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-	case SendStickerIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-	case SendVideoIDQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.genericSendPostData(url, val)
-		// Path
-	case SendPhotoPathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	case SendAudioPathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	case SendVoicePathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	case SendDocumentPathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	case SendStickerPathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	case SendVideoPathQuery:
-		hookPayload(&val, bot.DefaultOptions)
-		return bot.sendConvertingFile(url, ignore, file, val)
-	default:
-		ipath, err := reflections.GetField(val, ignore)
-		if err != nil {
-			break
-		}
-		params := convertInterfaceMap(val, []string{ignore})
-		return bot.uploadFileWithResult(url, params, file, ipath)
-	}
-	errc := 400
-	errs := "Wrong Query!"
-	return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
+// APIError is an error answered by Telegram.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
 }
 
-func (bot TgBot) genericSendPostData(url string, payload interface{}) ResultWithMessage {
-	// hook the payload :P
-	body, error := postPetition(url, payload, nil)
-	if error != nil {
-		errc := 500
-		err := "Some error happened while sending the message"
-		return ResultWithMessage{ResultBase{false, &errc, &err}, nil}
-	}
-	var result ResultWithMessage
-	json.Unmarshal([]byte(body), &result)
-	return result
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Error in petition %s.\nError code: %d\nDescription: %s", e.Method, e.Code, e.Description)
 }
 
-func (bot TgBot) sendConvertingFile(url string, ignore string, file string, val interface{}) ResultWithMessage {
-	ipath, err := reflections.GetField(val, ignore)
-	if err != nil {
-		errc := 400
-		errs := "Wrong Query!"
-		return ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
-	}
-	fpath := fmt.Sprintf("%+v", ipath)
-	params := convertInterfaceMap(val, []string{ignore})
-	return bot.uploadFileWithResult(url, params, file, fpath)
+// apiResponse is the answer of all the methods, only the result changes.
+type apiResponse[T any] struct {
+	Ok          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Result      T      `json:"result"`
 }
 
-func (bot TgBot) uploadFileWithResult(url string, params map[string]string, fieldname string, filename interface{}) ResultWithMessage {
-	res, err := bot.uploadFile(url, params, fieldname, filename)
-	if err != nil {
-		errc := 500
-		errs := err.Error()
-		res = ResultWithMessage{ResultBase{false, &errc, &errs}, nil}
-	}
-	return res
-}
-
-func (bot TgBot) uploadFileNoResult(url string, params map[string]string, fieldname string, filename interface{}) ([]byte, error) {
-	defaultb := make([]byte, 0)
-
-	var b bytes.Buffer
-	var err error
-	w := multipart.NewWriter(&b)
-	var fw io.Writer
-
-	switch rfile := filename.(type) {
-	case string:
-		rfile = filepath.Clean(rfile)
-		f, err := os.Open(rfile)
-		if err != nil {
-			return defaultb, err
-		}
-
-		fw, err := w.CreateFormFile(fieldname, rfile)
-		if err != nil {
-			return defaultb, err
-		}
-
-		if _, err = io.Copy(fw, f); err != nil {
-			return defaultb, err
-		}
-	case ReaderSender:
-		if fw, err = w.CreateFormFile(fieldname, rfile.Name); err != nil {
-			return defaultb, err
-		}
-		if _, err = io.Copy(fw, rfile.Read); err != nil {
-			return defaultb, err
-		}
-	case *gif.GIF:
-		if fw, err = w.CreateFormFile("document", "image.gif"); err != nil {
-			return defaultb, err
-		}
-		if err = gif.EncodeAll(fw, rfile); err != nil {
-			return defaultb, err
-		}
-	case image.Image:
-		imageQuality := jpeg.Options{Quality: jpeg.DefaultQuality}
-		if fw, err = w.CreateFormFile("photo", "image.jpeg"); err != nil {
-			return defaultb, err
-		}
-		if err = jpeg.Encode(fw, rfile, &imageQuality); err != nil {
-			return defaultb, err
-		}
+// Call calls the method of the API with the params and decodes the result, any struct with JSON tags works as params.
+// The params with files to upload (the *PathQuery, SetWebhookQuery with a certificate, or WithFiles) are streamed in a multipart form.
+// The default options of the bot are applied to the params. A nil ctx uses the context of the bot.
+//
+// If the call goes inside the webhook response, Telegram doesn't answer it, and the result is the zero value.
+func Call[T any](ctx context.Context, bot TgBot, method string, params interface{}) (T, error) {
+	var result T
+	if ctx != nil {
+		bot.ctx = ctx
 	}
+	params = bot.withDefaults(params)
 
-	for key, val := range params {
-		if fw, err = w.CreateFormField(key); err != nil {
-			return defaultb, err
-		}
-
-		if _, err = fw.Write([]byte(val)); err != nil {
-			return defaultb, err
-		}
+	apiurl := bot.buildPath(method)
+	if len(uploadsOf(params)) == 0 && bot.replyInResponse && bot.reply.claim(method, params) {
+		bot.recordCall(apiurl, params, "")
+		return result, nil
 	}
 
-	w.Close()
-
-	req, err := http.NewRequest("POST", url, &b)
+	bot.log().Debug("Calling the API", "method", method)
+	body, err := bot.callAPI(apiurl, params)
 	if err != nil {
-		return defaultb, err
+		err = bot.redactError(err)
+		bot.log().Warn("Error calling the API", "method", method, "error", err)
+		return result, err
 	}
+	var res apiResponse[T]
+	if err := json.Unmarshal(body, &res); err != nil {
+		return result, err
+	}
+	if !res.Ok {
+		return result, &APIError{method, res.ErrorCode, res.Description}
+	}
+	return res.Result, nil
+}
 
-	req.Header.Set("Content-Type", w.FormDataContentType())
-
-	client := &http.Client{}
-	res, err := client.Do(req)
+// callAPI does the request recording and tracing it, the request carries the context of its span.
+func (bot TgBot) callAPI(apiurl string, params interface{}) ([]byte, error) {
+	spanBot, done := bot.startAPICall(apiurl, params)
+	req, err := spanBot.newAPIRequest(apiurl, params)
 	if err != nil {
-		return defaultb, err
+		done("", err)
+		return nil, err
 	}
-
-	bytes, err := ioutil.ReadAll(res.Body)
-
+	res, err := bot.client().Do(req)
 	if err != nil {
-		return defaultb, err
+		done("", err)
+		return nil, err
 	}
-	return bytes, nil
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	done(string(body), err)
+	return body, err
 }
 
-func (bot TgBot) uploadFile(url string, params map[string]string, fieldname string, filename interface{}) (ResultWithMessage, error) {
-	bytes, err := bot.uploadFileNoResult(url, params, fieldname, filename)
+// newAPIRequest encodes the params in a form, multipart if they have files.
+func (bot TgBot) newAPIRequest(apiurl string, params interface{}) (*http.Request, error) {
+	form, err := formValues(params)
 	if err != nil {
-		return ResultWithMessage{}, err
+		return nil, err
 	}
-	var apiResp ResultWithMessage
-	json.Unmarshal(bytes, &apiResp)
-
-	return apiResp, nil
+	if files := uploadsOf(params); len(files) > 0 {
+		return bot.newMultipartRequest(apiurl, form, files)
+	}
+	req, err := http.NewRequestWithContext(bot.Context(), "POST", apiurl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
 }
 
-// func (bot TgBot) uploadFile(url string, params map[string]string, fieldname string, filename interface{}) (ResultWithMessage, error) {
-// 	var b bytes.Buffer
-// 	var err error
-// 	w := multipart.NewWriter(&b)
-// 	var fw io.Writer
-
-// 	switch rfile := filename.(type) {
-// 	case string:
-// 		rfile = filepath.Clean(rfile)
-// 		f, err := os.Open(rfile)
-// 		if err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-
-// 		fw, err := w.CreateFormFile(fieldname, rfile)
-// 		if err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-
-// 		if _, err = io.Copy(fw, f); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 	case *gif.GIF:
-// 		if fw, err = w.CreateFormFile("document", "image.gif"); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 		if err = gif.EncodeAll(fw, rfile); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 	case image.Image:
-// 		imageQuality := jpeg.Options{Quality: jpeg.DefaultQuality}
-// 		if fw, err = w.CreateFormFile("photo", "image.jpeg"); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 		if err = jpeg.Encode(fw, rfile, &imageQuality); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 	}
-
-// 	for key, val := range params {
-// 		if fw, err = w.CreateFormField(key); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-
-// 		if _, err = fw.Write([]byte(val)); err != nil {
-// 			return ResultWithMessage{}, err
-// 		}
-// 	}
-
-// 	w.Close()
-
-// 	req, err := http.NewRequest("POST", url, &b)
-// 	if err != nil {
-// 		return ResultWithMessage{}, err
-// 	}
-
-// 	req.Header.Set("Content-Type", w.FormDataContentType())
-
-// 	client := &http.Client{}
-// 	res, err := client.Do(req)
-// 	if err != nil {
-// 		return ResultWithMessage{}, err
-// 	}
-
-// 	bytes, err := ioutil.ReadAll(res.Body)
-
-// 	if err != nil {
-// 		return ResultWithMessage{}, err
-// 	}
-
-// 	var apiResp ResultWithMessage
-// 	json.Unmarshal(bytes, &apiResp)
+// resultBase converts the error of Call in the ResultBase of the old results.
+func resultBase(err error) ResultBase {
+	if err == nil {
+		return ResultBase{true, nil, nil}
+	}
+	errc, errs := 500, err.Error()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		errc, errs = apiErr.Code, apiErr.Description
+	}
+	return ResultBase{false, &errc, &errs}
+}
 
-// 	return apiResp, nil
-// }
+// callMessage calls a method that sends a message.
+func (bot TgBot) callMessage(method string, params interface{}) ResultWithMessage {
+	msg, err := Call[*Message](bot.Context(), bot, method, params)
+	res := ResultWithMessage{resultBase(err), msg}
+	bot.trackOutgoing(method, res, params)
+	return res
+}
@@ -0,0 +1,164 @@
+package tgbot
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How many update IDs are remembered to detect duplicated updates.
+const seenUpdatesWindow = 1000
+
+// updateTracker keeps the offset and the already seen updates, it's shared between all the copies of the bot.
+type updateTracker struct {
+	mu       sync.Mutex
+	offset   int64
+	pending  map[int]bool // update ID -> finished, only used in at-least-once mode
+	seen     map[int]struct{}
+	order    []int
+	progress chan struct{}
+}
+
+func newUpdateTracker() *updateTracker {
+	return &updateTracker{
+		pending:  map[int]bool{},
+		seen:     map[int]struct{}{},
+		order:    make([]int, 0, seenUpdatesWindow),
+		progress: make(chan struct{}, 1),
+	}
+}
+
+// begin register a new update, returns false if it's a duplicate.
+// In at-least-once mode the offset is not moved until the update is done.
+func (t *updateTracker) begin(id int, atLeastOnce bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.seen[id]; ok {
+		return false
+	}
+	t.remember(id)
+	if atLeastOnce {
+		t.pending[id] = false
+	} else if int64(id) > t.offset {
+		t.offset = int64(id)
+	}
+	return true
+}
+
+// done marks the update as finished and commits the offset up to the first update that is still running.
+func (t *updateTracker) done(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[id]; !ok {
+		return
+	}
+	t.pending[id] = true
+
+	ids := make([]int, 0, len(t.pending))
+	for pid := range t.pending {
+		ids = append(ids, pid)
+	}
+	sort.Ints(ids)
+	for _, pid := range ids {
+		if !t.pending[pid] {
+			break
+		}
+		if int64(pid) > t.offset {
+			t.offset = int64(pid)
+		}
+		delete(t.pending, pid)
+	}
+
+	select {
+	case t.progress <- struct{}{}:
+	default:
+	}
+}
+
+// forget removes an update that was not accepted, so it can be received again.
+func (t *updateTracker) forget(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.seen, id)
+	delete(t.pending, id)
+}
+
+// committed returns the last update ID that is safe to acknowledge to Telegram.
+func (t *updateTracker) committed() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offset
+}
+
+// wait blocks until some pending update is done or the timeout expires.
+func (t *updateTracker) wait(d time.Duration) {
+	select {
+	case <-t.progress:
+	case <-time.After(d):
+	}
+}
+
+func (t *updateTracker) remember(id int) {
+	t.seen[id] = struct{}{}
+	t.order = append(t.order, id)
+	if len(t.order) > seenUpdatesWindow {
+		delete(t.seen, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// advanceOffset moves the offset forward, never backwards.
+func advanceOffset(offset *int64, id int64) {
+	for {
+		current := atomic.LoadInt64(offset)
+		if id <= current || atomic.CompareAndSwapInt64(offset, current, id) {
+			return
+		}
+	}
+}
+
+// committedOffset returns the last update ID that can be acknowledged in getUpdates.
+func (bot TgBot) committedOffset() int64 {
+	offset := atomic.LoadInt64(&bot.LastUpdateID)
+	if bot.updates != nil {
+		if c := bot.updates.committed(); c > offset {
+			offset = c
+		}
+	}
+	return offset
+}
+
+// track registers the update, returns false if it was already received.
+func (bot *TgBot) track(msg MessageWithUpdateID) bool {
+	if bot.updates == nil {
+		advanceOffset(&bot.LastUpdateID, int64(msg.UpdateID))
+		return true
+	}
+	return bot.updates.begin(msg.UpdateID, bot.DefaultOptions.AtLeastOnce)
+}
+
+// receive deduplicates the update and sends it to the MainListener, returns false if the listener rejected it.
+func (bot *TgBot) receive(msg MessageWithUpdateID, canReject bool) bool {
+	if !bot.track(msg) {
+		// Already received, just ignore it.
+		msg.reply.finish()
+		return true
+	}
+
+	if !bot.enqueue(msg, canReject) {
+		if bot.updates != nil {
+			bot.updates.forget(msg.UpdateID)
+		}
+		return false
+	}
+	return true
+}
+
+// MarkProcessed tells the bot that the update has been handled. In at-least-once mode the offset only moves after this.
+// The default messages handler call it for you, if you use your own listener call it when you are done with each update.
+func (bot TgBot) MarkProcessed(updateID int) {
+	if bot.updates != nil {
+		bot.updates.done(updateID)
+	}
+}
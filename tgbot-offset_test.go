@@ -0,0 +1,90 @@
+package tgbot
+
+import "testing"
+
+func TestUpdateTrackerDuplicates(t *testing.T) {
+	tr := newUpdateTracker()
+	if !tr.begin(1, false) {
+		t.Fatal("the first update was taken as a duplicate")
+	}
+	if tr.begin(1, false) {
+		t.Error("the duplicated update was accepted")
+	}
+	tr.forget(1)
+	if !tr.begin(1, false) {
+		t.Error("the forgotten update was not accepted again")
+	}
+}
+
+func TestUpdateTrackerSeenWindow(t *testing.T) {
+	tr := newUpdateTracker()
+	for id := 1; id <= seenUpdatesWindow+1; id++ {
+		tr.begin(id, false)
+	}
+	if !tr.begin(1, false) {
+		t.Error("the update out of the window is still remembered")
+	}
+	if tr.begin(seenUpdatesWindow, false) {
+		t.Error("the update in the window was accepted again")
+	}
+}
+
+func TestUpdateTrackerAtMostOnce(t *testing.T) {
+	tr := newUpdateTracker()
+	tr.begin(5, false)
+	tr.begin(3, false)
+	if got := tr.committed(); got != 5 {
+		t.Errorf("committed = %d, want 5", got)
+	}
+}
+
+func TestUpdateTrackerAtLeastOnce(t *testing.T) {
+	tr := newUpdateTracker()
+	for _, id := range []int{1, 2, 3} {
+		tr.begin(id, true)
+	}
+	if got := tr.committed(); got != 0 {
+		t.Errorf("committed before any update is done = %d, want 0", got)
+	}
+
+	tr.done(2)
+	if got := tr.committed(); got != 0 {
+		t.Errorf("committed with the first update running = %d, want 0", got)
+	}
+	tr.done(1)
+	if got := tr.committed(); got != 2 {
+		t.Errorf("committed after 1 and 2 are done = %d, want 2", got)
+	}
+	tr.done(3)
+	if got := tr.committed(); got != 3 {
+		t.Errorf("committed after all are done = %d, want 3", got)
+	}
+}
+
+func TestAdvanceOffsetNeverGoesBack(t *testing.T) {
+	var offset int64
+	advanceOffset(&offset, 10)
+	advanceOffset(&offset, 4)
+	if offset != 10 {
+		t.Errorf("offset = %d, want 10", offset)
+	}
+}
+
+func TestProcessUpdatesSkipsReceived(t *testing.T) {
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.AddMainListener(make(chan MessageWithUpdateID, 10))
+
+	updates := []MessageWithUpdateID{{UpdateID: 7}, {UpdateID: 8}}
+	if fresh := bot.processUpdates(updates); fresh != 2 {
+		t.Errorf("fresh = %d, want 2", fresh)
+	}
+	if fresh := bot.processUpdates(updates); fresh != 0 {
+		t.Errorf("fresh with the same updates = %d, want 0", fresh)
+	}
+	if got := bot.committedOffset(); got != 8 {
+		t.Errorf("committedOffset = %d, want 8", got)
+	}
+	if got := len(bot.MainListener); got != 2 {
+		t.Errorf("updates in the listener = %d, want 2", got)
+	}
+}
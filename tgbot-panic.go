@@ -0,0 +1,103 @@
+package tgbot
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is the error given to the error handler when a handler panics.
+type PanicError struct {
+	Handler string
+	Value   interface{}
+	Stack   []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", pe.Handler, pe.Value)
+}
+
+// SetErrorHandler sets the function called when a handler panics (with a *PanicError), it runs after the panic is logged.
+func (bot *TgBot) SetErrorHandler(f func(TgBot, Message, error)) *TgBot {
+	bot.ErrorHandler = f
+	return bot
+}
+
+// SetPanicChat makes the bot send a message to the chat (for example your private chat with it) when a handler panics.
+func (bot *TgBot) SetPanicChat(cid int) *TgBot {
+	bot.DefaultOptions.PanicChat = cid
+	return bot
+}
+
+// callHandler calls the handler in its own span, recording how long it took and if it panicked.
+// With RecoverPanic the panic is reported and the other handlers still run, without it the panic goes on.
+func (bot TgBot) callHandler(c ConditionCallStructure, msg Message) {
+	if bot.Metrics == nil && bot.Tracer == nil && !bot.DefaultOptions.RecoverPanic {
+		c.call(bot, msg)
+		return
+	}
+	name := handlerName(c)
+	bot, span := bot.startSpan("tgbot.handler " + name)
+	span.SetAttribute("handler", name)
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			bot.metrics().Handler(name, time.Since(start), true)
+			span.RecordError(fmt.Errorf("panic: %v", r))
+			span.End()
+			if !bot.DefaultOptions.RecoverPanic {
+				panic(r)
+			}
+			bot.handlePanic(msg, &PanicError{name, r, debug.Stack()})
+		}
+	}()
+	c.call(bot, msg)
+	bot.metrics().Handler(name, time.Since(start), false)
+	span.End()
+}
+
+// handlePanic logs the panic and reports it to the error handler and the panic chat.
+func (bot TgBot) handlePanic(msg Message, pe *PanicError) {
+	bot.replyInResponse = false
+	defer func() {
+		// The error handler, or the hooks of the report sent, can panic too
+		if r := recover(); r != nil {
+			bot.log().Error("There was some panic reporting a panic", "handler", pe.Handler, "panic", fmt.Sprint(r))
+		}
+	}()
+	bot.log().Error("There was some panic", "handler", pe.Handler, "panic", fmt.Sprint(pe.Value), "chat_id", msg.Chat.ID, "stack", string(pe.Stack))
+	if bot.ErrorHandler != nil {
+		bot.ErrorHandler(bot, msg, pe)
+	}
+	if bot.DefaultOptions.PanicChat != 0 {
+		text := redactToken(fmt.Sprintf("%s\n\n%s", pe.Error(), pe.Stack), bot.Token)
+		text = SplitText(text, MessageLimit)[0]
+		bot.SendMessage(bot.DefaultOptions.PanicChat, text, nil, nil, nil, nil)
+	}
+}
+
+// safely runs f, with RecoverPanic its panic is reported like the ones of the handlers and false is returned.
+func (bot TgBot) safely(name string, msg Message, f func()) (ok bool) {
+	if !bot.DefaultOptions.RecoverPanic {
+		f()
+		return true
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			bot.handlePanic(msg, &PanicError{name, r, debug.Stack()})
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
+
+// handlerCanCall checks the condition of the handler, with RecoverPanic a condition that panics is false.
+func (bot TgBot) handlerCanCall(c ConditionCallStructure, msg Message) bool {
+	if !bot.DefaultOptions.RecoverPanic {
+		return c.canCall(bot, msg)
+	}
+	can := false
+	bot.safely(handlerName(c), msg, func() { can = c.canCall(bot, msg) })
+	return can
+}
@@ -0,0 +1,105 @@
+package tgbot
+
+import (
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// panicBot returns a bot that recovers the panics, reporting them to its error handler and to the chat 99.
+// The messages sent are kept in sent instead of going to Telegram.
+func panicBot() (*TgBot, *[]url.Values, *[]error) {
+	var mu sync.Mutex
+	sent := &[]url.Values{}
+	reported := &[]error{}
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.SetRecoverPanic(true).SetPanicChat(99)
+	bot.SetLogger(slog.New(slog.NewTextHandler(ioutil.Discard, nil)))
+	bot.SetErrorHandler(func(bot TgBot, msg Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		*reported = append(*reported, err)
+	})
+	bot.SetHTTPClient(&http.Client{Transport: transportFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		form, _ := url.ParseQuery(string(body))
+		mu.Lock()
+		*sent = append(*sent, form)
+		mu.Unlock()
+		res := `{"ok":true,"result":{"message_id":1,"chat":{"id":99},"date":1}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(res)), Header: http.Header{}}, nil
+	})})
+	return bot, sent, reported
+}
+
+func textUpdate(id int, text string) MessageWithUpdateID {
+	return MessageWithUpdateID{UpdateID: id, Msg: Message{ID: id, Chat: UserGroup{ID: 7}, Text: &text}}
+}
+
+func TestHandlerPanicIsReported(t *testing.T) {
+	bot, sent, reported := panicBot()
+	ran := false
+	bot.SimpleCommandFn(`boom`, func(bot TgBot, msg Message, text string) *string {
+		panic("kaboom")
+	})
+	bot.SimpleCommandFn(`boom`, func(bot TgBot, msg Message, text string) *string {
+		ran = true
+		return nil
+	})
+
+	bot.processUpdate(textUpdate(1, "/boom"))
+	if !ran {
+		t.Error("the handler after the panic didn't run")
+	}
+	var pe *PanicError
+	if len(*reported) != 1 || !errors.As((*reported)[0], &pe) || pe.Value != "kaboom" || len(pe.Stack) == 0 {
+		t.Fatalf("errors reported = %v, want the PanicError", *reported)
+	}
+	if len(*sent) != 1 || (*sent)[0].Get("chat_id") != "99" || !strings.Contains((*sent)[0].Get("text"), "kaboom") {
+		t.Errorf("messages sent = %v, want the report in the chat 99", *sent)
+	}
+}
+
+func TestHandlerPanicWithoutRecover(t *testing.T) {
+	bot, _, _ := panicBot()
+	bot.SetRecoverPanic(false)
+	bot.SimpleCommandFn(`boom`, func(bot TgBot, msg Message, text string) *string {
+		panic("kaboom")
+	})
+	defer func() {
+		if r := recover(); r != "kaboom" {
+			t.Errorf("recovered %v, want the panic of the handler", r)
+		}
+	}()
+	bot.processUpdate(textUpdate(1, "/boom"))
+	t.Error("the panic was recovered")
+}
+
+// panicWriter panics when the recorder writes to it.
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) { panic("disk on fire") }
+
+func TestHookPanicsAreRecovered(t *testing.T) {
+	bot, _, reported := panicBot()
+	bot.SetRecorder(NewRecorder(panicWriter{}))
+	bot.SetAnalytics(AnalyticsFunc(func(ev AnalyticsEvent) { panic("analytics down") }))
+	ran := false
+	bot.SimpleCommandFn(`start`, func(bot TgBot, msg Message, text string) *string {
+		ran = true
+		return nil
+	})
+
+	bot.processUpdate(textUpdate(1, "/start"))
+	if !ran {
+		t.Error("the handler didn't run after the hooks panicked")
+	}
+	if len(*reported) != 2 {
+		t.Errorf("errors reported = %v, want the recorder and the analytics ones", *reported)
+	}
+}
@@ -2,7 +2,9 @@ package tgbot
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 func (bot TgBot) addUsernameCommand(expr string) string {
@@ -39,12 +41,26 @@ func (bot TgBot) cleanMessage(msg Message) Message {
 			text := *msg.Text
 			username := fmt.Sprintf("@%s", bot.Username)
 			if strings.HasPrefix(text, username) {
-				text = strings.TrimSpace(strings.Replace(text, username, "", 1)) // Replace one time
+				trimmed := strings.TrimLeftFunc(strings.Replace(text, username, "", 1), unicode.IsSpace) // Replace one time
+				removed := UTF16Len(text) - UTF16Len(trimmed)
+				text = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+				slash := false
 				if bot.DefaultOptions.AllowWithoutSlashInMention &&
 					!strings.HasSuffix(text, "/") {
 					text = "/" + text
+					removed--
+					slash = true
 				}
 				msg.Text = &text
+				if msg.Entities != nil {
+					// The entities are moved with the text, and the command added gets its bot_command entity
+					entities := shiftEntities(*msg.Entities, removed)
+					if slash && len(strings.Fields(text)) > 0 {
+						command := MessageEntity{Type: "bot_command", Offset: 0, Length: UTF16Len(strings.Fields(text)[0])}
+						entities = append([]MessageEntity{command}, entities...)
+					}
+					msg.Entities = &entities
+				}
 			}
 		}
 
@@ -55,3 +71,44 @@ func (bot TgBot) cleanMessage(msg Message) Message {
 	}
 	return msg
 }
+
+// updateTyper is implemented by the handlers that need other updates than messages.
+type updateTyper interface {
+	updateTypes() []string
+}
+
+// handlerUpdateTypes returns the update types declared by the handler, the handlers that don't declare them only get messages.
+func handlerUpdateTypes(c ConditionCallStructure) []string {
+	if u, ok := c.(updateTyper); ok {
+		return u.updateTypes()
+	}
+	return []string{"message"}
+}
+
+// AllowedUpdates returns the update types needed by the registered handlers, nil if there are no handlers.
+func (bot TgBot) AllowedUpdates() []string {
+	handlers := []ConditionCallStructure{}
+	handlers = append(handlers, bot.TestConditionalFuncs...)
+	for _, nm := range bot.NoMessageFuncs {
+		handlers = append(handlers, nm)
+	}
+	for _, c := range bot.ChainConditionals {
+		handlers = append(handlers, c.chainf...)
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	types := map[string]bool{}
+	for _, h := range handlers {
+		for _, t := range handlerUpdateTypes(h) {
+			types[t] = true
+		}
+	}
+	allowed := make([]string, 0, len(types))
+	for t := range types {
+		allowed = append(allowed, t)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
@@ -0,0 +1,148 @@
+package tgbot
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy says what to do with a new update when the MainListener channel is full.
+type OverflowPolicy int
+
+// This is the enumerable
+const (
+	// QueueBlock waits until the listener accepts the update (the default).
+	QueueBlock OverflowPolicy = iota
+	// QueueDropOldest discards the oldest queued update to make room for the new one.
+	QueueDropOldest
+	// QueueReject refuses the update, the webhook answers 429 so Telegram retries it later.
+	// With getUpdates there is no way to refuse, so it behaves as QueueBlock.
+	QueueReject
+)
+
+var overflowpolicy = [...]string{
+	"block",
+	"drop_oldest",
+	"reject",
+}
+
+func (op OverflowPolicy) String() string {
+	if op < 0 || int(op) >= len(overflowpolicy) {
+		return fmt.Sprintf("OverflowPolicy(%d)", int(op))
+	}
+	return overflowpolicy[op]
+}
+
+// QueueStats is a snapshot of the MainListener queue.
+type QueueStats struct {
+	Depth    int   `json:"depth"`
+	Capacity int   `json:"capacity"`
+	Enqueued int64 `json:"enqueued"`
+	Dropped  int64 `json:"dropped"`
+	Rejected int64 `json:"rejected"`
+}
+
+type queueCounters struct {
+	enqueued int64
+	dropped  int64
+	rejected int64
+}
+
+// QueueStats return the current depth of the MainListener and how many updates were enqueued, dropped and rejected.
+func (bot TgBot) QueueStats() QueueStats {
+	stats := QueueStats{}
+	if bot.MainListener != nil {
+		stats.Depth = len(bot.MainListener)
+		stats.Capacity = cap(bot.MainListener)
+	}
+	if bot.queue != nil {
+		stats.Enqueued = atomic.LoadInt64(&bot.queue.enqueued)
+		stats.Dropped = atomic.LoadInt64(&bot.queue.dropped)
+		stats.Rejected = atomic.LoadInt64(&bot.queue.rejected)
+	}
+	return stats
+}
+
+// enqueue send the message to the MainListener following the overflow policy, returns false if it was rejected.
+func (bot TgBot) enqueue(msg MessageWithUpdateID, canReject bool) bool {
+	if bot.MainListener == nil {
+		return false
+	}
+
+	msg.queued = time.Now()
+	policy := bot.DefaultOptions.OverflowPolicy
+	if policy == QueueReject && !canReject {
+		policy = QueueBlock
+	}
+
+	switch policy {
+	case QueueDropOldest:
+		ch := bot.MainListener
+		for {
+			select {
+			case ch <- msg:
+				bot.countEnqueued()
+				return true
+			default:
+			}
+			if cap(ch) == 0 {
+				// Without the buffer there is nothing to drop, it waits like QueueBlock
+				ch <- msg
+				bot.countEnqueued()
+				return true
+			}
+			// Only a full buffer is drained, the update received is then the oldest one and not the one of another sender
+			if len(ch) == cap(ch) {
+				select {
+				case old := <-ch:
+					bot.queue.countDropped()
+					old.reply.finish()
+					bot.MarkProcessed(old.UpdateID)
+				default:
+				}
+			}
+		}
+	case QueueReject:
+		select {
+		case bot.MainListener <- msg:
+			bot.countEnqueued()
+			return true
+		default:
+			bot.queue.countRejected()
+			return false
+		}
+	default:
+		select {
+		case bot.MainListener <- msg:
+		default:
+			start := time.Now()
+			bot.MainListener <- msg
+			bot.metrics().Wait("queue", time.Since(start))
+		}
+		bot.countEnqueued()
+		return true
+	}
+}
+
+func (bot TgBot) countEnqueued() {
+	bot.queue.countEnqueued()
+	bot.metrics().QueueDepth(len(bot.MainListener))
+}
+
+func (q *queueCounters) countEnqueued() {
+	if q != nil {
+		atomic.AddInt64(&q.enqueued, 1)
+	}
+}
+
+func (q *queueCounters) countDropped() {
+	if q != nil {
+		atomic.AddInt64(&q.dropped, 1)
+	}
+}
+
+func (q *queueCounters) countRejected() {
+	if q != nil {
+		atomic.AddInt64(&q.rejected, 1)
+	}
+}
@@ -0,0 +1,130 @@
+package tgbot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func queueBot(policy OverflowPolicy, buffer int) *TgBot {
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.SetOverflowPolicy(policy)
+	bot.AddMainListener(make(chan MessageWithUpdateID, buffer))
+	return bot
+}
+
+// enqueueAsync enqueues the update in a goroutine, the result arrives when enqueue returns.
+func enqueueAsync(bot *TgBot, id int, canReject bool) chan bool {
+	res := make(chan bool, 1)
+	go func() {
+		res <- bot.enqueue(MessageWithUpdateID{UpdateID: id}, canReject)
+	}()
+	return res
+}
+
+func TestQueueBlock(t *testing.T) {
+	bot := queueBot(QueueBlock, 1)
+	bot.enqueue(MessageWithUpdateID{UpdateID: 1}, true)
+	res := enqueueAsync(bot, 2, true)
+	select {
+	case <-res:
+		t.Fatal("the update was enqueued in the full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := (<-bot.MainListener).UpdateID; got != 1 {
+		t.Errorf("first update = %d, want 1", got)
+	}
+	if !<-res {
+		t.Error("the blocked update was not enqueued")
+	}
+	if got := (<-bot.MainListener).UpdateID; got != 2 {
+		t.Errorf("second update = %d, want 2", got)
+	}
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	bot := queueBot(QueueDropOldest, 2)
+	for id := 1; id <= 3; id++ {
+		if !bot.enqueue(MessageWithUpdateID{UpdateID: id}, true) {
+			t.Fatalf("update %d was not enqueued", id)
+		}
+	}
+	if got := []int{(<-bot.MainListener).UpdateID, (<-bot.MainListener).UpdateID}; got[0] != 2 || got[1] != 3 {
+		t.Errorf("queued updates = %v, want [2 3]", got)
+	}
+	if stats := bot.QueueStats(); stats.Dropped != 1 || stats.Enqueued != 3 {
+		t.Errorf("stats = %+v, want 1 dropped and 3 enqueued", stats)
+	}
+}
+
+// Several senders with the queue full never take the update of another sender as the oldest one.
+func TestQueueDropOldestConcurrent(t *testing.T) {
+	bot := queueBot(QueueDropOldest, 1)
+	results := []chan bool{}
+	for id := 1; id <= 50; id++ {
+		results = append(results, enqueueAsync(bot, id, true))
+	}
+	for _, res := range results {
+		if !<-res {
+			t.Fatal("an update was not enqueued")
+		}
+	}
+	stats := bot.QueueStats()
+	if stats.Enqueued != 50 || stats.Dropped != 49 || len(bot.MainListener) != 1 {
+		t.Errorf("stats = %+v, want 50 enqueued, 49 dropped and one queued", stats)
+	}
+}
+
+// Without a buffer nothing is dropped, the senders wait and can't take the update of each other.
+func TestQueueDropOldestUnbuffered(t *testing.T) {
+	bot := queueBot(QueueDropOldest, 0)
+	results := []chan bool{enqueueAsync(bot, 1, true), enqueueAsync(bot, 2, true)}
+	time.Sleep(50 * time.Millisecond)
+	got := map[int]bool{}
+	for range results {
+		select {
+		case msg := <-bot.MainListener:
+			got[msg.UpdateID] = true
+		case <-time.After(time.Second):
+			t.Fatalf("updates received = %v, want 1 and 2", got)
+		}
+	}
+	for _, res := range results {
+		if !<-res {
+			t.Error("an update was not enqueued")
+		}
+	}
+	if stats := bot.QueueStats(); stats.Dropped != 0 {
+		t.Errorf("%d updates dropped without a buffer", stats.Dropped)
+	}
+}
+
+func TestQueueReject(t *testing.T) {
+	bot := queueBot(QueueReject, 1)
+	bot.enqueue(MessageWithUpdateID{UpdateID: 1}, true)
+	if bot.enqueue(MessageWithUpdateID{UpdateID: 2}, true) {
+		t.Error("the update was accepted in the full queue")
+	}
+	if stats := bot.QueueStats(); stats.Rejected != 1 {
+		t.Errorf("rejected = %d, want 1", stats.Rejected)
+	}
+
+	// getUpdates can't refuse the updates, they wait
+	res := enqueueAsync(bot, 3, false)
+	<-bot.MainListener
+	if !<-res {
+		t.Error("the update of getUpdates was rejected")
+	}
+}
+
+func TestQueueRejectWebhook(t *testing.T) {
+	bot := queueBot(QueueReject, 1)
+	bot.MainListener <- MessageWithUpdateID{UpdateID: 1}
+	w := httptest.NewRecorder()
+	bot.WebhookHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id":2}`)))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", w.Code)
+	}
+}
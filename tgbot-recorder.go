@@ -0,0 +1,397 @@
+package tgbot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordEntry is a line of a recording, an update received or a call to the API.
+type RecordEntry struct {
+	Time     time.Time            `json:"time"`
+	Kind     string               `json:"kind"`                // "update" or "call"
+	UpdateID int                  `json:"update_id,omitempty"` // In the calls, the update that the handler was processing
+	Update   *MessageWithUpdateID `json:"update,omitempty"`
+	Method   string               `json:"method,omitempty"`
+	Params   map[string]string    `json:"params,omitempty"`
+	Files    map[string]string    `json:"files,omitempty"` // The name of the files uploaded by field
+	Response json.RawMessage      `json:"response,omitempty"`
+}
+
+// Recorder writes the updates received and the calls to the API as JSON lines, to reproduce them later with tgbottest.Replay.
+// The token is always removed.
+type Recorder struct {
+	// RedactPII replaces the names and usernames, and the user and chat IDs with fake ones (the same fake ID for the same real one).
+	// The texts are kept, they are needed to reproduce the bugs, but the names of the users are replaced in the params and the responses of the calls too.
+	// The results that aren't messages or updates (chats, members, files...) are dropped, and so are the locations of the inline queries.
+	RedactPII bool
+
+	mu    sync.Mutex
+	enc   *json.Encoder
+	c     io.Closer
+	ids   map[int]int
+	names map[string]string
+}
+
+// NewRecorder writes the recording to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), ids: map[int]int{}, names: map[string]string{}}
+}
+
+// OpenRecorder appends the recording to the file, creating it if it doesn't exist.
+func OpenRecorder(name string) (*Recorder, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	rec := NewRecorder(f)
+	rec.c = f
+	return rec, nil
+}
+
+// Close closes the file if it was opened with OpenRecorder.
+func (rec *Recorder) Close() error {
+	if rec.c == nil {
+		return nil
+	}
+	return rec.c.Close()
+}
+
+// SetRecorder makes the bot write the updates and API calls to the recorder, with polling and with webhooks.
+func (bot *TgBot) SetRecorder(rec *Recorder) *TgBot {
+	bot.Recorder = rec
+	return bot
+}
+
+// ReadRecording reads all the entries of a recording.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	entries := []RecordEntry{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry RecordEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func (bot TgBot) recordUpdate(input MessageWithUpdateID) {
+	if bot.Recorder == nil {
+		return
+	}
+	bot.Recorder.write(bot.Token, RecordEntry{
+		Time:     time.Now(),
+		Kind:     "update",
+		UpdateID: input.UpdateID,
+		Update:   &input,
+	})
+}
+
+func (bot TgBot) recordCall(apiurl string, payload interface{}, body string) {
+	if bot.Recorder == nil {
+		return
+	}
+	params, files := callParams(payload)
+	entry := RecordEntry{
+		Time:     time.Now(),
+		Kind:     "call",
+		UpdateID: bot.updateID,
+		Method:   path.Base(apiurl),
+		Params:   params,
+		Files:    files,
+	}
+	if json.Valid([]byte(body)) {
+		entry.Response = json.RawMessage(body)
+	}
+	bot.Recorder.write(bot.Token, entry)
+}
+
+// callParams returns the parameters of a call like they are sent, the files uploaded go apart with their names.
+func callParams(payload interface{}) (map[string]string, map[string]string) {
+	params := map[string]string{}
+	switch val := payload.(type) {
+	case nil:
+	case map[string]string:
+		for k, v := range val {
+			params[k] = v
+		}
+	case []string:
+		for _, q := range val {
+			if values, err := url.ParseQuery(q); err == nil {
+				for k, v := range values {
+					params[k] = v[0]
+				}
+			}
+		}
+	default:
+		form, _ := formValues(val)
+		for k, v := range form {
+			params[k] = v[0]
+		}
+	}
+	var files map[string]string
+	for _, f := range uploadsOf(payload) {
+		if files == nil {
+			files = map[string]string{}
+		}
+		delete(params, f.Field)
+		files[f.Field] = fileName(f.File)
+	}
+	return params, files
+}
+
+func (rec *Recorder) write(token string, entry RecordEntry) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.RedactPII {
+		rec.redactEntry(&entry)
+	}
+	for k, v := range entry.Params {
+		entry.Params[k] = redactToken(v, token)
+	}
+	if entry.Response != nil {
+		entry.Response = json.RawMessage(redactToken(string(entry.Response), token))
+	}
+	rec.enc.Encode(entry)
+}
+
+// fakeID returns the fake ID of a user or chat, the groups keep being negative.
+func (rec *Recorder) fakeID(id int) int {
+	if id == 0 {
+		return 0
+	}
+	fake, ok := rec.ids[id]
+	if !ok {
+		fake = len(rec.ids) + 1
+		if id < 0 {
+			fake = -fake
+		}
+		rec.ids[id] = fake
+	}
+	return fake
+}
+
+// Params with the ID of a user or chat, faked like the IDs of the updates.
+var idParams = []string{"chat_id", "from_chat_id", "user_id"}
+
+func (rec *Recorder) redactEntry(entry *RecordEntry) {
+	if entry.Update != nil {
+		upd := rec.redactUpdate(*entry.Update)
+		entry.Update = &upd
+	}
+	for _, k := range idParams {
+		if id, err := strconv.Atoi(entry.Params[k]); err == nil {
+			entry.Params[k] = strconv.Itoa(rec.fakeID(id))
+		}
+	}
+	if entry.Response != nil {
+		entry.Response = rec.redactResponse(entry.Method, entry.Response)
+	}
+	replacer := rec.namesReplacer()
+	for k, v := range entry.Params {
+		if !isIDParam(k) {
+			entry.Params[k] = replacer.Replace(v)
+		}
+	}
+}
+
+func isIDParam(k string) bool {
+	for _, p := range idParams {
+		if k == p {
+			return true
+		}
+	}
+	return false
+}
+
+// redactUpdate redacts the message or query of the update, and learns the names of its users.
+func (rec *Recorder) redactUpdate(upd Update) Update {
+	if upd.Msg.ID != 0 {
+		rec.learnNames(upd.Msg)
+		upd.Msg = rec.redactMessage(upd.Msg)
+	}
+	if cq := upd.CallbackQuery; cq != nil {
+		redacted := *cq
+		rec.learnUser(cq.From)
+		redacted.From = rec.redactUser(cq.From)
+		if cq.Message != nil {
+			rec.learnNames(*cq.Message)
+			msg := rec.redactMessage(*cq.Message)
+			redacted.Message = &msg
+		}
+		upd.CallbackQuery = &redacted
+	}
+	if iq := upd.InlineQuery; iq != nil {
+		redacted := *iq
+		rec.learnUser(iq.From)
+		redacted.From = rec.redactUser(iq.From)
+		redacted.Location = nil
+		upd.InlineQuery = &redacted
+	}
+	return upd
+}
+
+// redactResponse redacts the messages answered and the updates of getUpdates.
+// The other results (chats, members, files...) are dropped, only the booleans and numbers are kept.
+func (rec *Recorder) redactResponse(method string, response json.RawMessage) json.RawMessage {
+	var res apiResponse[json.RawMessage]
+	if json.Unmarshal(response, &res) != nil {
+		return nil
+	}
+	if !res.Ok || len(res.Result) == 0 {
+		return response
+	}
+
+	var updates []Update
+	var msg Message
+	var msgs []Message
+	switch {
+	case method == "getUpdates" && json.Unmarshal(res.Result, &updates) == nil:
+		for i, upd := range updates {
+			updates[i] = rec.redactUpdate(upd)
+		}
+		res.Result, _ = json.Marshal(updates)
+	case json.Unmarshal(res.Result, &msg) == nil && msg.ID != 0:
+		res.Result, _ = json.Marshal(rec.redactSentMessage(msg))
+	case json.Unmarshal(res.Result, &msgs) == nil && len(msgs) > 0 && msgs[0].ID != 0:
+		for i, m := range msgs {
+			msgs[i] = rec.redactSentMessage(m)
+		}
+		res.Result, _ = json.Marshal(msgs)
+	default:
+		var scalar interface{}
+		json.Unmarshal(res.Result, &scalar)
+		switch scalar.(type) {
+		case bool, float64:
+		default:
+			res.Result = nil
+		}
+	}
+	redacted, _ := json.Marshal(res)
+	return redacted
+}
+
+// redactSentMessage redacts a message sent by the bot, its texts can quote the names like the params.
+func (rec *Recorder) redactSentMessage(msg Message) Message {
+	msg = rec.redactMessage(msg)
+	replacer := rec.namesReplacer()
+	for _, text := range []**string{&msg.Text, &msg.Caption} {
+		if *text != nil {
+			replaced := replacer.Replace(**text)
+			*text = &replaced
+		}
+	}
+	return msg
+}
+
+func (rec *Recorder) redactMessage(msg Message) Message {
+	msg.From = rec.redactUser(msg.From)
+	msg.Chat.ID = rec.fakeID(msg.Chat.ID)
+	if msg.Chat.Title == nil {
+		// Only the private chats have the names of the user
+		msg.Chat.FirstName, msg.Chat.LastName, msg.Chat.Username = nil, nil, nil
+	}
+	for _, u := range []**User{&msg.ForwardFrom, &msg.NewChatParticipant, &msg.LeftChatParticipant} {
+		if *u != nil {
+			redacted := rec.redactUser(**u)
+			*u = &redacted
+		}
+	}
+	for _, entities := range []**[]MessageEntity{&msg.Entities, &msg.CaptionEntities} {
+		if *entities != nil {
+			redacted := rec.redactEntities(**entities)
+			*entities = &redacted
+		}
+	}
+	if msg.ReplyToMessage != nil {
+		reply := rec.redactMessage(*msg.ReplyToMessage)
+		msg.ReplyToMessage = &reply
+	}
+	return msg
+}
+
+// redactEntities redacts the users of the text mentions.
+func (rec *Recorder) redactEntities(entities []MessageEntity) []MessageEntity {
+	redacted := make([]MessageEntity, len(entities))
+	for i, e := range entities {
+		if e.User != nil {
+			u := rec.redactUser(*e.User)
+			e.User = &u
+		}
+		redacted[i] = e
+	}
+	return redacted
+}
+
+// learnNames remembers the fake names of the users of a message.
+func (rec *Recorder) learnNames(msg Message) {
+	users := []*User{&msg.From, msg.ForwardFrom, msg.NewChatParticipant, msg.LeftChatParticipant}
+	_, entities := msg.TextEntities()
+	for _, e := range entities {
+		users = append(users, e.User)
+	}
+	for _, u := range users {
+		if u != nil {
+			rec.learnUser(*u)
+		}
+	}
+	if msg.ReplyToMessage != nil {
+		rec.learnNames(*msg.ReplyToMessage)
+	}
+}
+
+// learnUser remembers the fake names of the user.
+func (rec *Recorder) learnUser(u User) {
+	fake := rec.redactUser(u)
+	if u.FirstName != "" {
+		rec.names[u.FirstName] = fake.FirstName
+	}
+	if u.LastName != nil && *u.LastName != "" {
+		rec.names[*u.LastName] = ""
+	}
+	if u.Username != nil && *u.Username != "" {
+		rec.names[*u.Username] = *fake.Username
+	}
+}
+
+// namesReplacer replaces the real names learned by their fake ones, the longest first.
+func (rec *Recorder) namesReplacer() *strings.Replacer {
+	names := make([]string, 0, len(rec.names))
+	for name := range rec.names {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	oldnew := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		oldnew = append(oldnew, name, rec.names[name])
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func (rec *Recorder) redactUser(u User) User {
+	u.ID = rec.fakeID(u.ID)
+	u.FirstName = "User"
+	u.LastName = nil
+	if u.Username != nil {
+		username := fmt.Sprintf("user%d", u.ID)
+		u.Username = &username
+	}
+	return u
+}
@@ -0,0 +1,85 @@
+package tgbot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func redactingBot() (TgBot, *bytes.Buffer) {
+	var buf bytes.Buffer
+	rec := NewRecorder(&buf)
+	rec.RedactPII = true
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.SetRecorder(rec)
+	return *bot, &buf
+}
+
+func alice() User {
+	username, last := "alice", "Liddell"
+	return User{ID: 987654321, FirstName: "Alice", LastName: &last, Username: &username}
+}
+
+func checkRedacted(t *testing.T, buf *bytes.Buffer) {
+	t.Helper()
+	for _, pii := range []string{"Alice", "alice", "Liddell", "987654321", "40.4"} {
+		if strings.Contains(buf.String(), pii) {
+			t.Errorf("the recording has %q:\n%s", pii, buf)
+		}
+	}
+}
+
+func TestRecorderRedactsCallbackQueries(t *testing.T) {
+	bot, buf := redactingBot()
+	text := "vote for her"
+	mention := MessageEntity{Type: "text_mention", Offset: 9, Length: 3, User: &[]User{alice()}[0]}
+	msg := Message{ID: 3, From: alice(), Chat: UserGroup{ID: 987654321}, Text: &text, Entities: &[]MessageEntity{mention}}
+	data := "yes"
+	bot.recordUpdate(Update{UpdateID: 1, CallbackQuery: &CallbackQuery{ID: "9", From: alice(), Message: &msg, ChatInstance: "1", Data: &data}})
+	bot.recordUpdate(Update{UpdateID: 2, InlineQuery: &InlineQuery{ID: "10", From: alice(), Query: "cats", Location: &Location{Latitude: 40.4, Longitude: -3.7}}})
+	checkRedacted(t, buf)
+
+	entries, err := ReadRecording(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cq := entries[0].Update.CallbackQuery
+	if cq.From.FirstName != "User" || cq.Message.From.ID != cq.From.ID || (*cq.Message.Entities)[0].User.ID != cq.From.ID {
+		t.Errorf("the same user has several fake IDs: %+v", cq)
+	}
+	if *cq.Data != "yes" || *cq.Message.Text != text {
+		t.Errorf("the data and texts of the update were changed: %+v", cq)
+	}
+}
+
+func TestRecorderRedactsTheCalls(t *testing.T) {
+	bot, buf := redactingBot()
+	bot.recordUpdate(Update{UpdateID: 1, Msg: Message{ID: 1, From: alice(), Chat: UserGroup{ID: 987654321}}})
+
+	member := `{"ok":true,"result":{"user":{"id":987654321,"first_name":"Alice","username":"alice"},"status":"member"}}`
+	bot.recordCall("https://api.telegram.org/bot1:abc/getChatMember", map[string]string{"chat_id": "-100", "user_id": "987654321"}, member)
+	sent := `{"ok":true,"result":{"message_id":2,"from":{"id":1,"first_name":"Bot"},"date":1,"chat":{"id":987654321,"first_name":"Alice"},"text":"Hi Alice Liddell"}}`
+	bot.recordCall("https://api.telegram.org/bot1:abc/sendMessage", map[string]string{"chat_id": "987654321", "text": "Hi Alice Liddell"}, sent)
+	bot.recordCall("https://api.telegram.org/bot1:abc/answerCallbackQuery", map[string]string{"callback_query_id": "9"}, `{"ok":true,"result":true}`)
+	checkRedacted(t, buf)
+
+	entries, err := ReadRecording(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res apiResponse[json.RawMessage]
+	json.Unmarshal(entries[1].Response, &res)
+	if !res.Ok || string(res.Result) != "null" {
+		t.Errorf("the chat member was not dropped: %s", entries[1].Response)
+	}
+	if entries[1].Params["user_id"] != entries[2].Params["chat_id"] {
+		t.Errorf("the user has several fake IDs: %v, %v", entries[1].Params, entries[2].Params)
+	}
+	if got := entries[2].Params["text"]; got != "Hi User " {
+		t.Errorf("text = %q, want %q", got, "Hi User ")
+	}
+	if got := string(entries[3].Response); got != `{"ok":true,"result":true}` {
+		t.Errorf("the boolean result was changed: %s", got)
+	}
+}
@@ -0,0 +1,274 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Storage is a key-value store that the bots of a registry share.
+type Storage interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Delete(key string)
+}
+
+// MemoryStorage is a Storage in memory, safe for concurrent use.
+type MemoryStorage struct {
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+// NewMemoryStorage creates an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{data: map[string]interface{}{}}
+}
+
+// Get ...
+func (ms *MemoryStorage) Get(key string) (interface{}, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	v, ok := ms.data[key]
+	return v, ok
+}
+
+// Set ...
+func (ms *MemoryStorage) Set(key string, value interface{}) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.data[key] = value
+}
+
+// Delete ...
+func (ms *MemoryStorage) Delete(key string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.data, key)
+}
+
+// BotHealth is the state of a bot in a registry.
+type BotHealth struct {
+	ID         int        `json:"id"`
+	Username   string     `json:"username"`
+	Received   int64      `json:"received"`
+	Rejected   int64      `json:"rejected"`
+	LastUpdate *time.Time `json:"last_update,omitempty"`
+	Queue      QueueStats `json:"queue"`
+}
+
+type registeredBot struct {
+	bot      *TgBot
+	handler  http.Handler
+	received int64
+	rejected int64
+	last     int64 // Unix nanoseconds of the last update
+
+	inflight sync.WaitGroup           // Requests being served
+	listener chan MessageWithUpdateID // The MainListener started by the registry, nil if the bot had one
+	stopped  chan struct{}            // Closed when the listener finishes
+}
+
+// BotRegistry receives the updates of many bots in one endpoint, the bots can be added and removed while it's running.
+// Each bot listens in WebhookPath(Prefix), mount HealthHandler wherever you want to expose the health of all the bots.
+type BotRegistry struct {
+	Prefix  string
+	URL     string  // If not empty, the webhooks are set to this base URL when the bots are registered
+	Storage Storage // Given to the registered bots that don't have one
+
+	mu         sync.RWMutex
+	bots       map[string]*registeredBot
+	middleware []func(http.Handler) http.Handler
+	regMu      sync.Mutex // Serializes Register and Deregister, they start and stop the MainListener of the bots
+}
+
+// NewBotRegistry creates a registry listening in prefix, with an in memory storage.
+func NewBotRegistry(prefix string) *BotRegistry {
+	return &BotRegistry{
+		Prefix:  path.Join("/", prefix),
+		Storage: NewMemoryStorage(),
+		bots:    map[string]*registeredBot{},
+	}
+}
+
+// Use adds middlewares that wrap the webhook handler of every bot, the first one is the outermost.
+func (br *BotRegistry) Use(mw ...func(http.Handler) http.Handler) *BotRegistry {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	br.middleware = append(br.middleware, mw...)
+	return br
+}
+
+// Register adds the bot to the registry, setting its webhook if the registry has an URL.
+// It fails if a bot with the same token is already registered, deregister it first.
+func (br *BotRegistry) Register(bot *TgBot) error {
+	tokenpath, err := tokenPath(bot.Token)
+	if err != nil {
+		return err
+	}
+	br.regMu.Lock()
+	defer br.regMu.Unlock()
+	br.mu.RLock()
+	_, exists := br.bots[tokenpath]
+	br.mu.RUnlock()
+	if exists {
+		return errors.New("The bot is already registered")
+	}
+
+	if br.URL != "" {
+		base, err := url.Parse(br.URL)
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+		}
+		hookurl, _ := base.Parse(strings.TrimPrefix(path.Join(br.Prefix, tokenpath), "/"))
+		remoteuri := hookurl.String()
+		if res := bot.SetWebhookWithOptions(SetWebhookQuery{URL: &remoteuri}); !res.Ok {
+			return errors.New(res.Description)
+		}
+	}
+
+	if bot.Storage == nil {
+		bot.Storage = br.Storage
+	}
+
+	rb := &registeredBot{bot: bot}
+	if bot.MainListener == nil {
+		rb.listener = make(chan MessageWithUpdateID, bot.DefaultOptions.ListenerBuffer)
+		rb.stopped = make(chan struct{})
+		bot.AddMainListener(rb.listener)
+		go func() {
+			bot.MessagesHandler(rb.listener)
+			close(rb.stopped)
+		}()
+	}
+	rb.handler = bot.WebhookHandler()
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	br.bots[tokenpath] = rb
+	return nil
+}
+
+// Deregister removes the bot from the registry, returns false if it wasn't registered.
+// If the registry started the MainListener of the bot, it's stopped after the updates received are processed.
+// The webhook is not deleted, Telegram will get 404 until you set or delete it.
+func (br *BotRegistry) Deregister(bot *TgBot) bool {
+	tokenpath, err := tokenPath(bot.Token)
+	if err != nil {
+		return false
+	}
+	br.regMu.Lock()
+	defer br.regMu.Unlock()
+	br.mu.Lock()
+	rb, ok := br.bots[tokenpath]
+	delete(br.bots, tokenpath)
+	br.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	// No request can reach the bot now, the ones in flight finish their enqueue before the listener is closed
+	rb.inflight.Wait()
+	if rb.listener != nil {
+		close(rb.listener)
+		<-rb.stopped
+		bot.MainListener = nil
+	}
+	return true
+}
+
+// Bots returns the registered bots.
+func (br *BotRegistry) Bots() []*TgBot {
+	br.mu.RLock()
+	defer br.mu.RUnlock()
+	bots := make([]*TgBot, 0, len(br.bots))
+	for _, rb := range br.bots {
+		bots = append(bots, rb.bot)
+	}
+	return bots
+}
+
+// Health returns the state of every registered bot.
+func (br *BotRegistry) Health() []BotHealth {
+	br.mu.RLock()
+	defer br.mu.RUnlock()
+	health := make([]BotHealth, 0, len(br.bots))
+	for _, rb := range br.bots {
+		bh := BotHealth{
+			ID:       rb.bot.ID,
+			Username: rb.bot.Username,
+			Received: atomic.LoadInt64(&rb.received),
+			Rejected: atomic.LoadInt64(&rb.rejected),
+			Queue:    rb.bot.QueueStats(),
+		}
+		if last := atomic.LoadInt64(&rb.last); last > 0 {
+			t := time.Unix(0, last)
+			bh.LastUpdate = &t
+		}
+		health = append(health, bh)
+	}
+	return health
+}
+
+// HealthHandler returns an http.Handler that answers the health of the bots in JSON, it's not mounted by the registry
+// because it shows the bots to anyone that can reach it.
+func (br *BotRegistry) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(br.Health())
+	})
+}
+
+func (br *BotRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dir, tokenpath := path.Split(path.Clean(r.URL.Path))
+	br.mu.RLock()
+	rb, ok := br.bots[tokenpath]
+	if ok {
+		// Added with the lock, so Deregister waits for the request after removing the bot
+		rb.inflight.Add(1)
+	}
+	middleware := br.middleware
+	br.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer rb.inflight.Done()
+	if path.Clean(dir) != br.Prefix {
+		http.NotFound(w, r)
+		return
+	}
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		rb.handler.ServeHTTP(sw, r)
+		atomic.AddInt64(&rb.received, 1)
+		atomic.StoreInt64(&rb.last, time.Now().UnixNano())
+		if sw.status == http.StatusTooManyRequests {
+			atomic.AddInt64(&rb.rejected, 1)
+		}
+	})
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// statusWriter remembers the status code written.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,67 @@
+package tgbot
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func postToRegistry(br *BotRegistry, tokenpath string, updateID int) int {
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,"chat":{"id":7},"text":"hi"}}`, updateID)
+	br.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hooks/"+tokenpath, strings.NewReader(body)))
+	return w.Code
+}
+
+func TestRegistryRejectsTheSameToken(t *testing.T) {
+	br := NewBotRegistry("hooks")
+	first := NewWithUser("1:abc", User{ID: 1})
+	if err := br.Register(first); err != nil {
+		t.Fatal(err)
+	}
+	defer br.Deregister(first)
+	if err := br.Register(NewWithUser("1:abc", User{ID: 1})); err == nil {
+		t.Error("the second bot with the same token was registered")
+	}
+	if bots := br.Bots(); len(bots) != 1 || bots[0] != first {
+		t.Errorf("bots = %v, want the first one", bots)
+	}
+}
+
+func TestRegistryDeregisterWithRequests(t *testing.T) {
+	br := NewBotRegistry("hooks")
+	bot := NewWithUser("1:abc", User{ID: 1})
+	if err := br.Register(bot); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if code := postToRegistry(br, "1abc", id); code != http.StatusOK && code != http.StatusNotFound {
+				t.Errorf("update %d: status %d", id, code)
+			}
+		}(i)
+	}
+	if !br.Deregister(bot) {
+		t.Error("the bot was not registered")
+	}
+	wg.Wait()
+	if bot.MainListener != nil {
+		t.Error("the listener started by the registry was not removed")
+	}
+
+	// It can be registered again, with a new listener
+	if err := br.Register(bot); err != nil {
+		t.Fatal(err)
+	}
+	if code := postToRegistry(br, "1abc", 100); code != http.StatusOK {
+		t.Errorf("status after registering again = %d, want 200", code)
+	}
+	br.Deregister(bot)
+}
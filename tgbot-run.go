@@ -0,0 +1,185 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	pollErrorWait         = time.Second
+	webhookCheckInterval  = time.Minute
+	defaultWebhookAddress = ":8443"
+)
+
+// RunMode says how Run receives the updates.
+type RunMode int
+
+// This is the enumerable
+const (
+	// RunAuto uses the webhook if RunConfig.WebhookURL is set, and long polling if not.
+	RunAuto RunMode = iota
+	RunPolling
+	RunWebhook
+)
+
+var runmode = [...]string{
+	"auto",
+	"polling",
+	"webhook",
+}
+
+func (rm RunMode) String() string {
+	if rm < 0 || int(rm) >= len(runmode) {
+		return fmt.Sprintf("RunMode(%d)", int(rm))
+	}
+	return runmode[rm]
+}
+
+// RunConfig configures how Run receives the updates.
+type RunConfig struct {
+	WebhookURL           string          // Public base URL, the WebhookPath is added to it
+	WebhookPath          string          // Prefix of the path where the updates are received
+	Addr                 string          // Address of the webhook server, default :8443
+	CertFile             string          // Serve HTTPS with this certificate file...
+	KeyFile              string          // ...and this key file
+	SelfSigned           *SelfSignedCert // Or with a self-signed certificate, uploaded to Telegram
+	Webhook              SetWebhookQuery // Other setWebhook options, the URL is filled by Run
+	FallbackToPolling    bool            // Switch to polling if getWebhookInfo reports delivery errors
+	WebhookCheckInterval time.Duration   // How often getWebhookInfo is checked, default one minute
+}
+
+// SetRunConfig sets the configuration used by Run.
+func (bot *TgBot) SetRunConfig(cfg RunConfig) *TgBot {
+	bot.RunConfig = cfg
+	return bot
+}
+
+// Run receives updates until the context is cancelled, with a webhook or long polling depending on the mode and the RunConfig.
+// The webhook is set or deleted explicitly at the start, and if FallbackToPolling is enabled and Telegram can't deliver
+// the updates to the webhook, it is deleted and the bot keeps working with long polling.
+func (bot *TgBot) Run(ctx context.Context, mode RunMode) error {
+	if bot.ID == 0 {
+		return errors.New("No ID, maybe the token is bad.")
+	}
+	cfg := bot.RunConfig
+	if mode == RunAuto {
+		mode = RunPolling
+		if cfg.WebhookURL != "" {
+			mode = RunWebhook
+		}
+	}
+	if bot.MainListener == nil {
+		bot.StartMainListener()
+	}
+
+	if mode == RunWebhook {
+		fallback, err := bot.runWebhook(ctx, cfg)
+		if err != nil || !fallback {
+			return err
+		}
+	}
+	return bot.runPolling(ctx)
+}
+
+func (bot *TgBot) runPolling(ctx context.Context) error {
+	if _, err := bot.DeleteWebhook(false); err != nil {
+		return err
+	}
+	bot.poll(ctx, false)
+	return nil
+}
+
+// runWebhook serves the webhook until the context is cancelled, returns true if it stopped to fall back to polling.
+func (bot *TgBot) runWebhook(ctx context.Context, cfg RunConfig) (bool, error) {
+	if cfg.WebhookURL == "" {
+		return false, errors.New("The webhook mode needs a WebhookURL")
+	}
+	hookpath, err := bot.WebhookPath(cfg.WebhookPath)
+	if err != nil {
+		return false, err
+	}
+	base, err := url.Parse(cfg.WebhookURL)
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	hookurl, _ := base.Parse(strings.TrimPrefix(hookpath, "/"))
+	q := cfg.Webhook
+	remoteuri := hookurl.String()
+	q.URL = &remoteuri
+
+	addr := cfg.Addr
+	if addr == "" {
+		addr = defaultWebhookAddress
+	}
+	mux := http.NewServeMux()
+	mux.Handle(hookpath, bot.WebhookHandler())
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fallback := make(chan struct{})
+	if cfg.FallbackToPolling {
+		go func() {
+			if bot.watchWebhook(wctx, cfg.WebhookCheckInterval) {
+				close(fallback)
+				cancel()
+			}
+		}()
+	}
+
+	if cfg.SelfSigned != nil {
+		err = bot.ListenAndServeSelfSigned(wctx, addr, q, cfg.SelfSigned, mux)
+	} else if res := bot.SetWebhookWithOptions(q); !res.Ok {
+		err = errors.New(res.Description)
+	} else if cfg.CertFile != "" {
+		err = ListenAndServeTLS(wctx, addr, cfg.CertFile, cfg.KeyFile, mux)
+	} else {
+		err = ListenAndServe(wctx, addr, mux)
+	}
+
+	select {
+	case <-fallback:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// watchWebhook checks getWebhookInfo until the context is cancelled,
+// returns true if Telegram reports delivery errors after the webhook was set and there are updates waiting.
+func (bot *TgBot) watchWebhook(ctx context.Context, interval time.Duration) bool {
+	if interval == 0 {
+		interval = webhookCheckInterval
+	}
+	since := time.Now().Unix()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		info, err := bot.GetWebhookInfo()
+		if err != nil {
+			continue
+		}
+		if info.LastErrorDate != nil && int64(*info.LastErrorDate) >= since && info.PendingUpdateCount > 0 {
+			return true
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,478 @@
+package tgbot
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Telegram limits, in characters (UTF-16 code units) after parsing the format.
+const (
+	MessageLimit = 4096
+	CaptionLimit = 1024
+)
+
+// SplitText splits the text in parts of at most limit characters, cutting between paragraphs, lines or words when it's possible.
+// With a limit under 1 the text is returned whole.
+func SplitText(text string, limit int) []string {
+	parts := []string{}
+	for _, c := range splitEntities(text, nil, limit) {
+		parts = append(parts, c.text)
+	}
+	return parts
+}
+
+// SplitFormatted splits the formatted text in parts of at most limit characters, like SplitText.
+// The entities cut are closed at the end of a part and opened again in the next one, so every part is valid in its parse mode.
+func SplitFormatted(ft FormattedText, limit int) []FormattedText {
+	text, entities := plainText(ft)
+	if UTF16Len(text) <= limit {
+		return []FormattedText{ft}
+	}
+
+	parts := []FormattedText{}
+	for _, c := range splitEntities(text, entities, limit) {
+		parts = append(parts, chunkFormatted(ft, c))
+	}
+	return parts
+}
+
+// plainText returns the text without format and its entities, reading the markup of the parse mode.
+func plainText(ft FormattedText) (string, []MessageEntity) {
+	mode := ft.ParseMode()
+	if mode == nil {
+		if e := ft.Entities(); e != nil {
+			return ft.Text(), *e
+		}
+		return ft.Text(), nil
+	}
+	if tb, ok := ft.(*TextBuilder); ok {
+		return tb.text.String(), tb.entities
+	}
+	return parseMarkup(ft.Text(), *mode)
+}
+
+// chunkFormatted returns the part of the text in the same format as ft.
+func chunkFormatted(ft FormattedText, c textChunk) FormattedText {
+	if mode := ft.ParseMode(); mode != nil {
+		return formattedText{renderEntities(c.text, c.entities, *mode), mode, nil}
+	}
+	if ft.Entities() != nil {
+		ents := c.entities
+		return formattedText{c.text, nil, &ents}
+	}
+	return formattedText{c.text, nil, nil}
+}
+
+// SendLongMessage sends the formatted text split in as many messages as needed, the reply goes in the first one and the keyboard in the last one.
+// It returns the messages sent, until the first error.
+func (bot TgBot) SendLongMessage(cid int, ft FormattedText, dwp *bool, rtmid *int, rm *ReplyMarkupInt) ([]Message, error) {
+	return bot.sendLongMessage(cid, ft, dwp, replyOptions(rtmid, rm))
+}
+
+func (bot TgBot) sendLongMessage(cid int, ft FormattedText, dwp *bool, opts SendOptions) ([]Message, error) {
+	parts := SplitFormatted(ft, MessageLimit)
+	msgs := []Message{}
+	for i, part := range parts {
+		partOpts := opts
+		if i > 0 {
+			partOpts.ReplyToMessageID = nil
+		}
+		if i < len(parts)-1 {
+			partOpts.ReplyMarkup = nil
+		}
+		msg, err := splitResultInMessageError(bot.sendMessage(cid, part, dwp, partOpts))
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// sendWithLongCaption sends a media with send, if the caption is too long the rest is sent in messages after it, with the markup in the last one.
+// The rest keeps the format of the caption.
+func (bot TgBot) sendWithLongCaption(cid int, caption FormattedText, opts SendOptions, send func(FormattedText, SendOptions) ResultWithMessage) ([]Message, error) {
+	caption, rest := splitCaption(caption)
+	mediaOpts := opts
+	if rest != nil {
+		mediaOpts.ReplyMarkup = nil
+	}
+	msg, err := splitResultInMessageError(send(caption, mediaOpts))
+	if err != nil {
+		return []Message{}, err
+	}
+	if rest == nil {
+		return []Message{msg}, nil
+	}
+	opts.ReplyToMessageID = nil
+	msgs, err := bot.sendLongMessage(cid, rest, nil, opts)
+	return append([]Message{msg}, msgs...), err
+}
+
+// splitCaption returns the part of the caption that fits in the media, and the rest to be sent in messages, nil if it fits.
+func splitCaption(caption FormattedText) (FormattedText, FormattedText) {
+	if caption == nil {
+		return nil, nil
+	}
+	text, entities := plainText(caption)
+	if UTF16Len(text) <= CaptionLimit {
+		return caption, nil
+	}
+	units := utf16.Encode([]rune(text))
+	end, start := spaceBounds(units, entities, cutPoint(units, 0, CaptionLimit))
+	first := chunkFormatted(caption, textChunk{decodeUnits(units[:end]), clipEntities(entities, 0, end)})
+	rest := chunkFormatted(caption, textChunk{decodeUnits(units[start:]), clipEntities(entities, start, len(units))})
+	return first, rest
+}
+
+// plainCaption returns the caption without format, nil if there is no caption.
+func plainCaption(caption *string) FormattedText {
+	if caption == nil {
+		return nil
+	}
+	return formattedText{*caption, nil, nil}
+}
+
+// captionFields returns the caption, parse mode and caption entities of the queries, all nil if there is no caption.
+func captionFields(caption FormattedText) (*string, *string, *[]MessageEntity) {
+	if caption == nil {
+		return nil, nil, nil
+	}
+	text := caption.Text()
+	var pm *string
+	if mode := caption.ParseMode(); mode != nil {
+		s := mode.String()
+		pm = &s
+	}
+	return &text, pm, caption.Entities()
+}
+
+// textChunk is a part of a split text, with the entities moved to it.
+type textChunk struct {
+	text     string
+	entities []MessageEntity
+}
+
+// splitEntities splits the text like SplitText, the entities are cut with the text.
+func splitEntities(text string, entities []MessageEntity, limit int) []textChunk {
+	if limit < 1 {
+		return []textChunk{{text, entities}}
+	}
+	units := utf16.Encode([]rune(text))
+	chunks := []textChunk{}
+	start := 0
+	for start < len(units) {
+		cut := len(units)
+		if len(units)-start > limit {
+			cut = cutPoint(units, start, limit)
+		}
+		end, next := spaceBounds(units, entities, cut)
+		if end > start {
+			chunks = append(chunks, textChunk{decodeUnits(units[start:end]), clipEntities(entities, start, end)})
+		}
+		start = next
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, textChunk{text, entities})
+	}
+	return chunks
+}
+
+// cutPoint returns where to cut the text starting at start, after the last paragraph, line or word that fits in the limit.
+func cutPoint(units []uint16, start int, limit int) int {
+	window := decodeUnits(units[start : start+limit])
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(window, sep); i > 0 {
+			return start + UTF16Len(window[:i+len(sep)])
+		}
+	}
+	cut := start + limit
+	if isHighSurrogate(units[cut-1]) {
+		// Don't break a surrogate pair, it goes whole to the next part, or to this one if it's the only thing that fits
+		if cut-1 > start {
+			cut--
+		} else {
+			cut++
+		}
+	}
+	return cut
+}
+
+// isHighSurrogate returns true if the unit is the first half of a surrogate pair.
+func isHighSurrogate(u uint16) bool {
+	return u >= 0xD800 && u < 0xDC00
+}
+
+// spaceBounds returns where the text before the cut ends and where the text after it starts, without the spaces around the cut.
+// The spaces inside a code or pre entity are kept, they are the indentation of the code.
+func spaceBounds(units []uint16, entities []MessageEntity, cut int) (end int, start int) {
+	end, start = cut, cut
+	for end > 0 && isSpaceUnit(units[end-1]) && !inCode(entities, end-1) {
+		end--
+	}
+	for start < len(units) && isSpaceUnit(units[start]) && !inCode(entities, start) {
+		start++
+	}
+	return end, start
+}
+
+// inCode returns true if the unit at pos is inside a code or pre entity.
+func inCode(entities []MessageEntity, pos int) bool {
+	for _, e := range entities {
+		if (e.Type == "code" || e.Type == "pre") && e.Offset <= pos && pos < e.Offset+e.Length {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpaceUnit(u uint16) bool {
+	return unicode.IsSpace(rune(u))
+}
+
+// clipEntities returns the parts of the entities between start and end, moved to start at 0.
+func clipEntities(entities []MessageEntity, start int, end int) []MessageEntity {
+	clipped := []MessageEntity{}
+	for _, e := range entities {
+		estart, eend := e.Offset, e.Offset+e.Length
+		if estart < start {
+			estart = start
+		}
+		if eend > end {
+			eend = end
+		}
+		if eend > estart {
+			e.Offset, e.Length = estart-start, eend-estart
+			clipped = append(clipped, e)
+		}
+	}
+	return clipped
+}
+
+// parseMarkup reads the text written in the parse mode, it returns the plain text and its entities.
+func parseMarkup(text string, mode ParseModeT) (string, []MessageEntity) {
+	if mode == Html {
+		return parseHTML(text)
+	}
+	return parseMarkdown(text, mode == MarkdownV2)
+}
+
+// markupWriter keeps the plain text and its length in UTF-16 while parsing.
+type markupWriter struct {
+	text     strings.Builder
+	length   int
+	entities []MessageEntity
+}
+
+func (mw *markupWriter) write(s string) {
+	mw.text.WriteString(s)
+	mw.length += UTF16Len(s)
+}
+
+// close adds the entity that started at its offset and ends at the current length.
+func (mw *markupWriter) close(e MessageEntity) {
+	e.Length = mw.length - e.Offset
+	if e.Type != "" && e.Length > 0 {
+		mw.entities = append(mw.entities, e)
+	}
+}
+
+func (mw *markupWriter) result() (string, []MessageEntity) {
+	sortEntities(mw.entities)
+	return mw.text.String(), mw.entities
+}
+
+var htmlTags = map[string]string{
+	"b": "bold", "strong": "bold",
+	"i": "italic", "em": "italic",
+	"u": "underline", "ins": "underline",
+	"s": "strikethrough", "strike": "strikethrough", "del": "strikethrough",
+	"tg-spoiler": "spoiler",
+	"code":       "code",
+	"pre":        "pre",
+	"blockquote": "blockquote",
+}
+
+var htmlAttribute = regexp.MustCompile(`([\w-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"']+))`)
+
+func parseHTML(text string) (string, []MessageEntity) {
+	type openTag struct {
+		name   string
+		entity MessageEntity
+	}
+	mw := &markupWriter{}
+	stack := []openTag{}
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			j := strings.IndexByte(text[i:], '>')
+			if j < 0 {
+				mw.write(text[i:])
+				i = len(text)
+				continue
+			}
+			tag := text[i+1 : i+j]
+			i += j + 1
+			if strings.HasPrefix(tag, "/") {
+				name := strings.ToLower(strings.TrimSpace(tag[1:]))
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k].name == name {
+						mw.close(stack[k].entity)
+						stack = stack[:k]
+						break
+					}
+				}
+				continue
+			}
+			fields := strings.Fields(tag)
+			if len(fields) == 0 {
+				continue
+			}
+			name := strings.ToLower(fields[0])
+			attrs := map[string]string{}
+			for _, m := range htmlAttribute.FindAllStringSubmatch(tag, -1) {
+				attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+			}
+			e := MessageEntity{Type: htmlTags[name], Offset: mw.length}
+			switch {
+			case name == "a":
+				e = linkEntity(attrs["href"], mw.length)
+			case name == "span" && attrs["class"] == "tg-spoiler":
+				e.Type = "spoiler"
+			case name == "code" && len(stack) > 0 && stack[len(stack)-1].name == "pre":
+				// The code inside pre only gives its language
+				if language := strings.TrimPrefix(attrs["class"], "language-"); language != attrs["class"] {
+					stack[len(stack)-1].entity.Language = &language
+				}
+				e.Type = ""
+			}
+			stack = append(stack, openTag{name, e})
+		case '&':
+			j := strings.IndexByte(text[i:], ';')
+			if j > 0 && j < 10 {
+				mw.write(html.UnescapeString(text[i : i+j+1]))
+				i += j + 1
+				continue
+			}
+			mw.write("&")
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(text[i:])
+			mw.write(text[i : i+size])
+			i += size
+		}
+	}
+	return mw.result()
+}
+
+// linkEntity returns the entity of a link, a mention if it links to a user.
+func linkEntity(url string, offset int) MessageEntity {
+	if id, err := strconv.Atoi(strings.TrimPrefix(url, "tg://user?id=")); err == nil && strings.HasPrefix(url, "tg://user?id=") {
+		return MessageEntity{Type: "text_mention", Offset: offset, User: &User{ID: id}}
+	}
+	return MessageEntity{Type: "text_link", Offset: offset, URL: &url}
+}
+
+func parseMarkdown(text string, v2 bool) (string, []MessageEntity) {
+	markers := []string{"*", "_"}
+	types := map[string]string{"*": "bold", "_": "italic", "__": "underline", "~": "strikethrough", "||": "spoiler"}
+	if v2 {
+		markers = []string{"||", "__", "*", "_", "~"}
+	}
+	mw := &markupWriter{}
+	open := map[string]int{}
+	links := []int{}
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && (v2 || strings.ContainsRune("_*`[", rune(rest[1]))):
+			_, size := utf8.DecodeRuneInString(rest[1:])
+			mw.write(rest[1 : 1+size])
+			i += 1 + size
+			continue
+		case v2 && rest[0] == '\r':
+			i++
+			continue
+		case strings.HasPrefix(rest, "```"):
+			if content, next, ok := scanUntil(text, i+3, "```", v2); ok {
+				language := ""
+				if nl := strings.IndexByte(content, '\n'); nl >= 0 {
+					language, content = content[:nl], content[nl+1:]
+				}
+				e := MessageEntity{Type: "pre", Offset: mw.length}
+				if language != "" {
+					e.Language = &language
+				}
+				mw.write(strings.TrimSuffix(content, "\n"))
+				mw.close(e)
+				i = next
+				continue
+			}
+		case rest[0] == '`':
+			if content, next, ok := scanUntil(text, i+1, "`", v2); ok {
+				e := MessageEntity{Type: "code", Offset: mw.length}
+				mw.write(content)
+				mw.close(e)
+				i = next
+				continue
+			}
+		case rest[0] == '[':
+			links = append(links, mw.length)
+			i++
+			continue
+		case rest[0] == ']' && len(links) > 0 && strings.HasPrefix(rest, "]("):
+			if url, next, ok := scanUntil(text, i+2, ")", v2); ok {
+				mw.close(linkEntity(url, links[len(links)-1]))
+				links = links[:len(links)-1]
+				i = next
+				continue
+			}
+		}
+		matched := false
+		for _, m := range markers {
+			if strings.HasPrefix(rest, m) {
+				if start, ok := open[m]; ok {
+					mw.close(MessageEntity{Type: types[m], Offset: start})
+					delete(open, m)
+				} else {
+					open[m] = mw.length
+				}
+				i += len(m)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(rest)
+			mw.write(rest[:size])
+			i += size
+		}
+	}
+	return mw.result()
+}
+
+// scanUntil reads the text from start until the delimiter, in MarkdownV2 the backslash escapes the next character.
+// It returns the text read and where it continues after the delimiter.
+func scanUntil(text string, start int, delim string, escapes bool) (string, int, bool) {
+	var sb strings.Builder
+	for i := start; i < len(text); {
+		if escapes && text[i] == '\\' && i+1 < len(text) {
+			_, size := utf8.DecodeRuneInString(text[i+1:])
+			sb.WriteString(text[i+1 : i+1+size])
+			i += 1 + size
+			continue
+		}
+		if strings.HasPrefix(text[i:], delim) {
+			return sb.String(), i + len(delim), true
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		sb.WriteString(text[i : i+size])
+		i += size
+	}
+	return "", start, false
+}
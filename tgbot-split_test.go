@@ -0,0 +1,125 @@
+package tgbot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTextCutsBetweenWords(t *testing.T) {
+	got := SplitText("one two three four", 9)
+	want := []string{"one two", "three", "four"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestSplitTextPrefersParagraphs(t *testing.T) {
+	got := SplitText("first line\nsecond\n\nthird", 20)
+	want := []string{"first line\nsecond", "third"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestSplitTextLimits(t *testing.T) {
+	text := strings.Repeat("a", 25)
+	for _, part := range SplitText(text, 10) {
+		if UTF16Len(part) > 10 {
+			t.Errorf("part of %d characters, over the limit", UTF16Len(part))
+		}
+	}
+	if got := SplitText("short", 10); !reflect.DeepEqual(got, []string{"short"}) {
+		t.Errorf("the short text was split: %q", got)
+	}
+}
+
+// The emojis take two UTF-16 units, the cut can't fall between them.
+func TestSplitTextSurrogates(t *testing.T) {
+	got := SplitText("😀😀😀", 4)
+	want := []string{"😀😀", "😀"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestSplitFormattedReopensEntities(t *testing.T) {
+	ft := NewMarkdownV2Builder().Plain("see ").Bold("very long words").Text()
+	mode := MarkdownV2
+	parts := SplitFormatted(formattedText{ft, &mode, nil}, 10)
+	got := []string{}
+	for _, p := range parts {
+		if p.ParseMode() == nil || *p.ParseMode() != MarkdownV2 {
+			t.Errorf("part %q lost the parse mode", p.Text())
+		}
+		got = append(got, p.Text())
+	}
+	want := []string{"see *very*", "*long words*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestSplitFormattedEntities(t *testing.T) {
+	tb := NewEntitiesBuilder().Plain("go to ").Link("the site", "https://e.com")
+	parts := SplitFormatted(tb, 8)
+	if len(parts) != 2 || parts[0].Text() != "go to" || parts[1].Text() != "the site" {
+		t.Fatalf("parts = %+v", parts)
+	}
+	if e := *parts[0].Entities(); len(e) != 0 {
+		t.Errorf("entities of the first part = %+v", e)
+	}
+	if e := *parts[1].Entities(); len(e) != 1 || e[0].Type != "text_link" || e[0].Offset != 0 || e[0].Length != 8 || *e[0].URL != "https://e.com" {
+		t.Errorf("entities of the second part = %+v", e)
+	}
+}
+
+func TestSplitCaption(t *testing.T) {
+	short := NewHTMLBuilder().Plain("short")
+	if first, rest := splitCaption(short); first != short || rest != nil {
+		t.Errorf("the short caption was split: %v, %v", first, rest)
+	}
+
+	// The emoji before the limit can't be cut in half
+	long := NewHTMLBuilder().Bold(strings.Repeat("a", CaptionLimit-1) + "😀 end")
+	first, rest := splitCaption(long)
+	if rest == nil {
+		t.Fatal("the long caption was not split")
+	}
+	if *first.ParseMode() != Html || *rest.ParseMode() != Html {
+		t.Error("the parts lost the parse mode")
+	}
+	if want := "<b>" + strings.Repeat("a", CaptionLimit-1) + "</b>"; first.Text() != want {
+		t.Errorf("caption = %q", first.Text())
+	}
+	if want := "<b>😀 end</b>"; rest.Text() != want {
+		t.Errorf("rest = %q, want %q", rest.Text(), want)
+	}
+}
+
+func TestSplitTextInvalidLimit(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		if got := SplitText("abc", limit); !reflect.DeepEqual(got, []string{"abc"}) {
+			t.Errorf("limit %d: parts = %q, want the text whole", limit, got)
+		}
+	}
+	if got := SplitText("😀😀", 1); !reflect.DeepEqual(got, []string{"😀", "😀"}) {
+		t.Errorf("limit 1: parts = %q, want the emojis whole", got)
+	}
+}
+
+func TestSplitFormattedKeepsTheIndentationOfPre(t *testing.T) {
+	code := "func f() {\n    return\n}"
+	tb := NewEntitiesBuilder().Pre(code, "go")
+	parts := SplitFormatted(tb, 12)
+	text := ""
+	for _, p := range parts {
+		text += p.Text()
+		if e := *p.Entities(); len(e) != 1 || e[0].Type != "pre" || e[0].Length != UTF16Len(p.Text()) {
+			t.Errorf("part %q has the entities %+v, want the whole pre", p.Text(), e)
+		}
+	}
+	if text != code {
+		t.Errorf("the parts joined are %q, want %q", text, code)
+	}
+}
@@ -0,0 +1,84 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+)
+
+// Tracer starts the spans of the bot, it looks like the OpenTelemetry tracer so it's easy to adapt one.
+// The bot starts a span for every update, one child for each handler called, and one for every request to the API done from a handler.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is one traced operation, End is called when it finishes.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// SetTracer sets the tracer that receives the spans of the bot.
+func (bot *TgBot) SetTracer(t Tracer) *TgBot {
+	bot.Tracer = t
+	return bot
+}
+
+// Context returns the context of the update being handled, with its span, use it to add your own spans as children.
+func (bot TgBot) Context() context.Context {
+	if bot.ctx == nil {
+		return context.Background()
+	}
+	return bot.ctx
+}
+
+// startSpan starts a child of the current span, the bot returned carries the new context.
+func (bot TgBot) startSpan(name string) (TgBot, Span) {
+	if bot.Tracer == nil {
+		return bot, nopSpan{}
+	}
+	ctx, span := bot.Tracer.Start(bot.Context(), name)
+	bot.ctx = ctx
+	return bot, span
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, interface{}) {}
+func (nopSpan) RecordError(error)                {}
+func (nopSpan) End()                             {}
+
+// startAPICall starts the span and the timer of a request to the API, call the returned function with the answer.
+// The bot returned carries the context of the span, to build the request with it.
+func (bot TgBot) startAPICall(url string, payload interface{}) (TgBot, func(body string, err error)) {
+	method := path.Base(url)
+	spanBot, span := bot.startSpan("tgbot.api " + method)
+	span.SetAttribute("method", method)
+	start := time.Now()
+	return spanBot, func(body string, err error) {
+		bot.recordCall(url, payload, body)
+		errc := apiErrorCode(body, err)
+		bot.metrics().APICall(method, time.Since(start), errc)
+		if errc != 0 {
+			span.SetAttribute("error_code", errc)
+			if err == nil {
+				err = errors.New("The API answered with an error")
+			}
+			span.RecordError(bot.redactError(err))
+		}
+		span.End()
+	}
+}
+
+// traceUpdate starts the span of an update, with how long it waited in the queue.
+func (bot TgBot) traceUpdate(input MessageWithUpdateID) (TgBot, Span) {
+	bot, span := bot.startSpan("tgbot.update")
+	span.SetAttribute("update_id", input.UpdateID)
+	span.SetAttribute("chat_id", input.Msg.Chat.ID)
+	if !input.queued.IsZero() {
+		span.SetAttribute("queue_wait_ms", time.Since(input.queued).Milliseconds())
+	}
+	return bot, span
+}
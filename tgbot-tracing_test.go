@@ -0,0 +1,41 @@
+package tgbot
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type spanKey struct{}
+
+// testTracer puts the name of the current span in the context.
+type testTracer struct{}
+
+func (testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return context.WithValue(ctx, spanKey{}, name), nopSpan{}
+}
+
+// transportFunc answers the requests without network.
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestAPIRequestInsideItsSpan(t *testing.T) {
+	var spanOfRequest interface{}
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.SetTracer(testTracer{})
+	bot.SetHTTPClient(&http.Client{Transport: transportFunc(func(r *http.Request) (*http.Response, error) {
+		spanOfRequest = r.Context().Value(spanKey{})
+		body := `{"ok":true,"result":{"id":1,"first_name":"Bot"}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})})
+
+	if _, err := bot.GetMe(); err != nil {
+		t.Fatal(err)
+	}
+	if spanOfRequest != "tgbot.api getMe" {
+		t.Errorf("the request was done in the span %v, want tgbot.api getMe", spanOfRequest)
+	}
+}
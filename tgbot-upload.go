@@ -0,0 +1,214 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// InputFile is a file to upload in the field of a request, the file can be a path, a ReaderSender, a *gif.GIF or an image.Image (sent in JPEG).
+// The files that other params refer to, like the media of sendMediaGroup or a thumbnail, use the field they name in "attach://<field>".
+type InputFile struct {
+	Field string
+	File  interface{}
+}
+
+// UploadProgress is the progress of a file upload, Total is -1 if the size is unknown.
+type UploadProgress struct {
+	Method string
+	Field  string
+	Sent   int64
+	Total  int64
+}
+
+// SetUploadProgress sets the function called while the files are uploaded, it's called from the goroutine that writes the request.
+func (bot *TgBot) SetUploadProgress(f func(UploadProgress)) *TgBot {
+	bot.UploadProgress = f
+	return bot
+}
+
+// WithFiles returns the params with the files to upload, to send them with Call.
+func WithFiles(params interface{}, files ...InputFile) interface{} {
+	return fileParams{params, files}
+}
+
+// uploader is implemented by the params that upload files, the files go in their fields instead of the values of the params.
+type uploader interface {
+	uploads() []InputFile
+}
+
+// uploadsOf returns the files to upload in the params.
+func uploadsOf(params interface{}) []InputFile {
+	u, ok := params.(uploader)
+	if !ok {
+		return nil
+	}
+	files := []InputFile{}
+	for _, f := range u.uploads() {
+		if f.File != nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (q SendPhotoPathQuery) uploads() []InputFile    { return []InputFile{{"photo", q.Photo}} }
+func (q SendAudioPathQuery) uploads() []InputFile    { return []InputFile{{"audio", q.Audio}} }
+func (q SendVoicePathQuery) uploads() []InputFile    { return []InputFile{{"voice", q.Voice}} }
+func (q SendDocumentPathQuery) uploads() []InputFile { return []InputFile{{"document", q.Document}} }
+func (q SendStickerPathQuery) uploads() []InputFile  { return []InputFile{{"sticker", q.Sticker}} }
+func (q SendVideoPathQuery) uploads() []InputFile    { return []InputFile{{"video", q.Video}} }
+func (q SetWebhookCertQuery) uploads() []InputFile {
+	return []InputFile{{"certificate", q.Certificate}}
+}
+func (q SetWebhookQuery) uploads() []InputFile { return []InputFile{{"certificate", q.Certificate}} }
+
+// fileParams are the params of a method with files that can't go in them, like an image.Image or a ReaderSender.
+type fileParams struct {
+	params interface{}
+	files  []InputFile
+}
+
+func (fp fileParams) MarshalJSON() ([]byte, error) { return json.Marshal(fp.params) }
+func (fp fileParams) uploads() []InputFile         { return fp.files }
+
+// filePart is a file ready to be written in the request.
+type filePart struct {
+	field string
+	name  string
+	size  int64 // -1 if it's unknown
+	write func(io.Writer) error
+	close func() error
+}
+
+// openPart opens the file, the paths are opened before sending the request so their errors are returned right away.
+func openPart(f InputFile) (filePart, error) {
+	part := filePart{field: f.Field, size: -1, close: func() error { return nil }}
+	switch file := f.File.(type) {
+	case string:
+		fh, err := os.Open(filepath.Clean(file))
+		if err != nil {
+			return part, err
+		}
+		if info, err := fh.Stat(); err == nil {
+			part.size = info.Size()
+		}
+		part.name = fileName(file)
+		part.write = func(w io.Writer) error {
+			_, err := io.Copy(w, fh)
+			return err
+		}
+		part.close = fh.Close
+	case ReaderSender:
+		part.name = fileName(file)
+		part.write = func(w io.Writer) error {
+			_, err := io.Copy(w, file.Read)
+			return err
+		}
+	case *gif.GIF:
+		part.name = fileName(file)
+		part.write = func(w io.Writer) error { return gif.EncodeAll(w, file) }
+	case image.Image:
+		part.name = fileName(file)
+		part.write = func(w io.Writer) error {
+			return jpeg.Encode(w, file, &jpeg.Options{Quality: jpeg.DefaultQuality})
+		}
+	default:
+		return part, errors.New("No file interface detected")
+	}
+	return part, nil
+}
+
+// fileName returns the name a file is uploaded with.
+func fileName(file interface{}) string {
+	switch file := file.(type) {
+	case string:
+		return filepath.Base(file)
+	case ReaderSender:
+		return file.Name
+	case *gif.GIF:
+		return "image.gif"
+	case image.Image:
+		return "image.jpeg"
+	}
+	return ""
+}
+
+// newMultipartRequest streams the form and the files in the body, the files are read while the request is sent and closed after it.
+func (bot TgBot) newMultipartRequest(apiurl string, form url.Values, files []InputFile) (*http.Request, error) {
+	parts := make([]filePart, 0, len(files))
+	closeParts := func() {
+		for _, part := range parts {
+			part.close()
+		}
+	}
+	for _, f := range files {
+		part, err := openPart(f)
+		if err != nil {
+			closeParts()
+			return nil, err
+		}
+		parts = append(parts, part)
+		form.Del(f.Field)
+	}
+
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	req, err := http.NewRequestWithContext(bot.Context(), "POST", apiurl, pr)
+	if err != nil {
+		closeParts()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	// The client closes the body when it's done, even if it fails, so the writer never waits forever
+	go func() {
+		defer closeParts()
+		pw.CloseWithError(bot.writeMultipart(w, path.Base(apiurl), form, parts))
+	}()
+	return req, nil
+}
+
+func (bot TgBot) writeMultipart(w *multipart.Writer, method string, form url.Values, parts []filePart) error {
+	for key, val := range form {
+		if err := w.WriteField(key, val[0]); err != nil {
+			return err
+		}
+	}
+	for _, part := range parts {
+		fw, err := w.CreateFormFile(part.field, part.name)
+		if err != nil {
+			return err
+		}
+		if bot.UploadProgress != nil {
+			fw = &progressWriter{fw, UploadProgress{method, part.field, 0, part.size}, bot.UploadProgress}
+		}
+		if err := part.write(fw); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// progressWriter reports the bytes written of a file.
+type progressWriter struct {
+	w        io.Writer
+	progress UploadProgress
+	report   func(UploadProgress)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.progress.Sent += int64(n)
+	pw.report(pw.progress)
+	return n, err
+}
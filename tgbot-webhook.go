@@ -0,0 +1,172 @@
+package tgbot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// How long the server waits for the running requests when the context is cancelled.
+const shutdownTimeout = 10 * time.Second
+
+// Max size of the body of a webhook request, the updates are much smaller.
+const maxUpdateSize = 1 << 20
+
+// Header where Telegram sends the secret token configured in setWebhook.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookPath returns the path where the bot receives the updates, the prefix joined with the token without the colon.
+func (bot TgBot) WebhookPath(prefix string) (string, error) {
+	tokenpath, err := tokenPath(bot.Token)
+	if err != nil {
+		return "", err
+	}
+	return path.Join("/", prefix, tokenpath), nil
+}
+
+func tokenPath(token string) (string, error) {
+	tokendiv := strings.Split(token, ":")
+	if len(tokendiv) != 2 {
+		return "", errors.New("Malformed token, it should look like <id>:<secret>")
+	}
+	return fmt.Sprintf("%s%s", tokendiv[0], tokendiv[1]), nil
+}
+
+// WebhookHandler returns an http.Handler that receives the updates that Telegram sends to this bot.
+// Mount it wherever you want (WebhookPath is the usual place), if the bot doesn't have a MainListener the default one is started.
+func (bot *TgBot) WebhookHandler() http.Handler {
+	if bot.MainListener == nil {
+		bot.StartMainListener()
+	}
+	return webhookHandler{bot}
+}
+
+type webhookHandler struct {
+	bot *TgBot
+}
+
+func (wh webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if secret := r.Header.Get(secretTokenHeader); !wh.bot.validSecret(secret) {
+		if secret == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		} else {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return
+	}
+
+	var msg MessageWithUpdateID
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&msg); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if msg.UpdateID > 0 {
+		if wh.bot.webhookReplies {
+			msg.reply = newWebhookReply()
+		}
+		if !wh.bot.receive(msg, true) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if msg.reply != nil {
+			timeout := wh.bot.DefaultOptions.WebhookReplyTimeout
+			if timeout == 0 {
+				timeout = webhookReplyTimeout
+			}
+			if body := msg.reply.wait(timeout, r.Context().Done()); body != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write(body)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// validSecret checks the secret token sent by Telegram, anything is valid if the bot doesn't have one.
+func (bot TgBot) validSecret(secret string) bool {
+	if bot.WebhookSecret == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(bot.WebhookSecret)) == 1
+}
+
+// MultiBotWebhookHandler returns an http.Handler that receives the updates of all the bots, each one in its WebhookPath(prefix).
+// Use a BotRegistry if you want to add or remove bots later.
+func MultiBotWebhookHandler(prefix string, bots ...*TgBot) (http.Handler, error) {
+	registry := NewBotRegistry(prefix)
+	for _, bot := range bots {
+		if err := registry.Register(bot); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// ListenAndServe serves the handler in addr until the context is cancelled, then it shuts down gracefully.
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	return serveUntilDone(ctx, srv, srv.ListenAndServe)
+}
+
+// ListenAndServeTLS is like ListenAndServe but with HTTPS, using the certificate and key files.
+func ListenAndServeTLS(ctx context.Context, addr string, certFile string, keyFile string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	return serveUntilDone(ctx, srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+func serveUntilDone(ctx context.Context, srv *http.Server, serve func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			return err
+		}
+		if err := <-errc; err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+}
+
+// legacyAddr keeps the old Martini behaviour, $HOST and $PORT (or 3000) when host or port are empty.
+func legacyAddr(host string, port string) string {
+	if host == "" || port == "" {
+		host = os.Getenv("HOST")
+		port = os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+	}
+	return host + ":" + port
+}
@@ -0,0 +1,56 @@
+package tgbot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveWebhook(bot *TgBot, r *http.Request) int {
+	w := httptest.NewRecorder()
+	bot.WebhookHandler().ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWebhookBodyTooLarge(t *testing.T) {
+	bot := NewWithUser("1:abc", User{ID: 1})
+	text := strings.Repeat("a", maxUpdateSize)
+	body := `{"update_id":1,"message":{"message_id":1,"chat":{"id":7},"text":"` + text + `"}}`
+	if code := serveWebhook(bot, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", code)
+	}
+	if code := serveWebhook(bot, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("{"))); code != http.StatusBadRequest {
+		t.Errorf("status of the broken JSON = %d, want 400", code)
+	}
+}
+
+func TestWebhookSecretToken(t *testing.T) {
+	bot := NewWithUser("1:abc", User{ID: 1})
+	bot.SetWebhookSecret("s3cret")
+	for _, tc := range []struct {
+		secret string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusForbidden},
+		{"s3cret", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id":1}`))
+		if tc.secret != "" {
+			r.Header.Set(secretTokenHeader, tc.secret)
+		}
+		if code := serveWebhook(bot, r); code != tc.want {
+			t.Errorf("secret %q: status = %d, want %d", tc.secret, code, tc.want)
+		}
+	}
+}
+
+func TestWebhookOnlyPost(t *testing.T) {
+	bot := NewWithUser("1:abc", User{ID: 1})
+	w := httptest.NewRecorder()
+	bot.WebhookHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hook", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET answered %d with Allow %q, want 405 with POST", w.Code, w.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,154 @@
+package tgbot
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default time that the webhook request waits for a handler to answer inside the response.
+const webhookReplyTimeout = 5 * time.Second
+
+// webhookReply is the open response of a webhook request, one API call can be sent inside it.
+type webhookReply struct {
+	mu       sync.Mutex
+	closed   bool
+	payload  chan []byte
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newWebhookReply() *webhookReply {
+	return &webhookReply{payload: make(chan []byte, 1), done: make(chan struct{})}
+}
+
+// claim puts the method call in the response, returns false if the response is already used or written,
+// or if the handler needs the result of the method.
+func (wr *webhookReply) claim(method string, payload interface{}) bool {
+	if wr == nil || !fireAndForget(method) {
+		return false
+	}
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if wr.closed {
+		return false
+	}
+
+	body, err := webhookReplyBody(method, payload)
+	if err != nil {
+		return false
+	}
+	wr.closed = true
+	wr.payload <- body
+	return true
+}
+
+// close stops accepting calls, returns false if a call was already claimed.
+func (wr *webhookReply) close() bool {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if wr.closed {
+		return false
+	}
+	wr.closed = true
+	return true
+}
+
+// finish tells the request that the handlers are done with the update.
+func (wr *webhookReply) finish() {
+	if wr == nil {
+		return
+	}
+	wr.doneOnce.Do(func() {
+		close(wr.done)
+	})
+}
+
+// wait blocks until a handler claims the response, the handlers finish or the timeout expires, and returns the body to write (if any).
+func (wr *webhookReply) wait(timeout time.Duration, cancel <-chan struct{}) []byte {
+	select {
+	case body := <-wr.payload:
+		return body
+	case <-wr.done:
+	case <-time.After(timeout):
+	case <-cancel:
+	}
+	if wr.close() {
+		return nil
+	}
+	return <-wr.payload
+}
+
+// fireAndForget returns true for the methods whose result the handlers don't need, the only ones that can go in the response.
+func fireAndForget(method string) bool {
+	switch method {
+	case "forwardMessage", "copyMessage":
+		return true
+	}
+	for _, prefix := range []string{"send", "edit", "answer", "delete"} {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookReplyBody(method string, payload interface{}) ([]byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	fields["method"], _ = json.Marshal(method)
+	return json.Marshal(fields)
+}
+
+// webhookReplyCall wraps a handler so its first API call is sent in the webhook response.
+type webhookReplyCall struct {
+	inner ConditionCallStructure
+}
+
+// canCall ...
+func (wrc webhookReplyCall) canCall(bot TgBot, msg Message) bool {
+	return wrc.inner.canCall(bot, msg)
+}
+
+// call ...
+func (wrc webhookReplyCall) call(bot TgBot, msg Message) {
+	bot.replyInResponse = true
+	wrc.inner.call(bot, msg)
+}
+
+// updateTypes ...
+func (wrc webhookReplyCall) updateTypes() []string {
+	return handlerUpdateTypes(wrc.inner)
+}
+
+// ReplyInWebhook makes the last added function answer inside the webhook response instead of doing a new request.
+// Only the first API call of the handler that sends, edits, answers or deletes (without uploading files) can go in the response,
+// and Telegram doesn't give back the result. The rest of the calls, or the ones done after the response has been written, are sent as always.
+func (bot *TgBot) ReplyInWebhook() *TgBot {
+	wrap := func(c ConditionCallStructure) ConditionCallStructure {
+		return webhookReplyCall{c}
+	}
+	if bot.BuildingChain {
+		if len(bot.ChainConditionals) > 0 {
+			bot.ChainConditionals[len(bot.ChainConditionals)-1].wrapLast(wrap)
+		}
+	} else if len(bot.TestConditionalFuncs) > 0 {
+		last := len(bot.TestConditionalFuncs) - 1
+		bot.TestConditionalFuncs[last] = wrap(bot.TestConditionalFuncs[last])
+	}
+	bot.webhookReplies = true
+	return bot
+}
+
+// SetWebhookReplyTimeout sets how long a webhook request waits for the handlers that answer inside the response.
+func (bot *TgBot) SetWebhookReplyTimeout(d time.Duration) *TgBot {
+	bot.DefaultOptions.WebhookReplyTimeout = d
+	return bot
+}
@@ -1,23 +1,23 @@
 package tgbot
 
 import (
+	"context"
 	"fmt"
-	"sync/atomic"
+	"net/http"
+	"time"
 
 	"net/url"
-	"path"
 	"strings"
-
-	"github.com/botanio/sdk/go"
-	"github.com/go-martini/martini"
-	"github.com/martini-contrib/binding"
-	"github.com/martini-contrib/gorelic"
+	"sync"
 )
 
 const (
-	baseURL = "https://api.telegram.org/bot%s/%s"
-	fileURL = "https://api.telegram.org/file/bot%s/%s"
+	apiURL  = "https://api.telegram.org"
+	baseURL = "%s/bot%s/%s"
+	fileURL = "%s/file/bot%s/%s"
 	timeout = 60
+
+	defaultWorkers = 16 // Updates processed at the same time by the default listener
 )
 
 // New creates an instance of a new bot with the token supplied, if it's invalid this method fail with a panic.
@@ -36,34 +36,74 @@ func NewTgBot(token string) *TgBot {
 
 // NewWithError creates an instance and return possible error
 func NewWithError(token string) (*TgBot, error) {
-	url := fmt.Sprintf(baseURL, token, "%s")
-	furl := fmt.Sprintf(fileURL, token, "%s")
-	tgbot := &TgBot{
+	return NewWithURL(token, apiURL)
+}
+
+// NewWithURL is like NewWithError but talking with the Bot API server in api (like "https://api.telegram.org"),
+// use it with a local Bot API server or a fake one in the tests.
+func NewWithURL(token string, api string) (*TgBot, error) {
+	tgbot := newBot(token, api)
+	user, err := tgbot.GetMe()
+	if err != nil {
+		return nil, err
+		// panic(err)
+	} else {
+		tgbot.setUser(user)
+	}
+	return tgbot, nil
+}
+
+// NewWithUser creates the bot without calling getMe, with the user that getMe would return.
+// Useful in the tests, or to start faster if you already know it.
+func NewWithUser(token string, user User) *TgBot {
+	tgbot := newBot(token, apiURL)
+	tgbot.setUser(user)
+	return tgbot
+}
+
+func newBot(token string, api string) *TgBot {
+	api = strings.TrimSuffix(api, "/")
+	url := fmt.Sprintf(baseURL, api, token, "%s")
+	furl := fmt.Sprintf(fileURL, api, token, "%s")
+	return &TgBot{
 		Token:                token,
 		BaseRequestURL:       url,
 		BaseFileRequestURL:   furl,
 		MainListener:         nil,
 		RelicCfg:             nil,
-		BotanIO:              nil,
 		TestConditionalFuncs: make([]ConditionCallStructure, 0),
 		NoMessageFuncs:       make([]NoMessageCall, 0),
 		ChainConditionals:    make([]*ChainStructure, 0),
 		BuildingChain:        false,
+		queue:                &queueCounters{},
+		updates:              newUpdateTracker(),
 		DefaultOptions: DefaultOptionsBot{
 			CleanInitialUsername:       true,
 			AllowWithoutSlashInMention: true,
+			RecoverPanic:               true,
 		},
 	}
-	user, err := tgbot.GetMe()
-	if err != nil {
-		return nil, err
-		// panic(err)
-	} else {
-		tgbot.FirstName = user.FirstName
-		tgbot.ID = user.ID
-		tgbot.Username = *user.Username
+}
+
+func (bot *TgBot) setUser(user User) {
+	bot.FirstName = user.FirstName
+	bot.ID = user.ID
+	if user.Username != nil {
+		bot.Username = *user.Username
 	}
-	return tgbot, nil
+}
+
+// SetHTTPClient sets the client used to call the API, for example to set a proxy or a timeout.
+func (bot *TgBot) SetHTTPClient(client *http.Client) *TgBot {
+	bot.HTTPClient = client
+	return bot
+}
+
+func (bot TgBot) client() *http.Client {
+	if bot.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return bot.HTTPClient
 }
 
 // TgBot basic bot struct that handle all the interaction functions.
@@ -75,7 +115,6 @@ type TgBot struct {
 	BaseRequestURL       string
 	BaseFileRequestURL   string
 	RelicCfg             *RelicConfig
-	BotanIO              *botan.Botan
 	MainListener         chan MessageWithUpdateID
 	LastUpdateID         int64
 	TestConditionalFuncs []ConditionCallStructure
@@ -83,8 +122,30 @@ type TgBot struct {
 	ChainConditionals    []*ChainStructure
 	BuildingChain        bool
 	DefaultOptions       DefaultOptionsBot
+	Polling              PollingOptions
+	WebhookSecret        string
+	RunConfig            RunConfig
+	Storage              Storage
+	Logger               Logger
+	Metrics              Metrics
+	Tracer               Tracer
+	Analytics            Analytics
+	ErrorHandler         func(TgBot, Message, error)
+	HTTPClient           *http.Client
+	Recorder             *Recorder
+	UploadProgress       func(UploadProgress)
+	queue                *queueCounters
+	updates              *updateTracker
+	webhookReplies       bool
+	reply                *webhookReply   // Open webhook response of the update being processed
+	replyInResponse      bool            // The handler being called can answer in the webhook response
+	ctx                  context.Context // Context of the update being processed, with its span
+	updateID             int             // Update being processed
 }
 
+// RelicConfig is the New Relic agent configuration.
+//
+// Deprecated: New Relic went with the martini server and is ignored, use SetMetrics or wrap the WebhookHandler with your agent.
 type RelicConfig struct {
 	Token string
 	Name  string
@@ -92,12 +153,14 @@ type RelicConfig struct {
 
 // ProcessAllMsg default message handler that take care of clean the messages, the chains and the action functions.
 func (bot TgBot) ProcessAllMsg(msg Message) {
-	msg = bot.cleanMessage(msg)
-	// Let's try with and without goroutines here
+	if !bot.safely("cleanMessage", msg, func() { msg = bot.cleanMessage(msg) }) {
+		return
+	}
+	// The chain is called in the same goroutine so the update is not marked as processed before it finishes
 	for _, c := range bot.ChainConditionals {
-		if c.canCall(bot, msg) {
-			// c.call(bot, msg)
-			go c.call(bot, msg)
+		if bot.handlerCanCall(c, msg) {
+			bot.callHandler(c, msg)
+			// go c.call(bot, msg)
 			return
 		}
 		if c.UserInChain(msg) {
@@ -111,40 +174,82 @@ func (bot TgBot) ProcessAllMsg(msg Message) {
 		// if nm, ok := v.(NoMessageCall); ok {
 		// 	execlater = append(execlater, nm)
 		// }
-		if v.canCall(bot, msg) {
+		if bot.handlerCanCall(v, msg) {
 			executed = true
-			v.call(bot, msg)
+			bot.callHandler(v, msg)
 			// go v.call(bot, msg)
 		}
 	}
 
 	if !executed {
 		for _, f := range bot.NoMessageFuncs {
-			f.call(bot, msg)
+			bot.callHandler(f, msg)
 		}
 	}
 }
 
-// MessagesHandler is the default listener, just listen for a channel and call the default message processor
+// MessagesHandler is the default listener, it processes the updates of the channel with the workers configured with SetWorkers,
+// when all of them are busy the channel backs up and the overflow policy decides. It returns when the channel is closed.
 func (bot TgBot) MessagesHandler(Incoming <-chan MessageWithUpdateID) {
-	for {
-		input := <-Incoming
-		go bot.ProcessAllMsg(input.Msg) // go this or not?
+	workers := bot.DefaultOptions.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
 	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for input := range Incoming {
+				bot.processUpdate(input)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// processUpdate calls the handlers with the update and marks it as processed when they finish.
+func (bot TgBot) processUpdate(input MessageWithUpdateID) {
+	bot.log().Debug("Update received", "update_id", input.UpdateID, "chat_id", input.Msg.Chat.ID)
+	bot, span := bot.traceUpdate(input)
+	defer span.End()
+	bot.updateID = input.UpdateID
+	// The hooks can panic like the handlers, with RecoverPanic they don't stop the worker
+	bot.safely("Recorder", input.Msg, func() { bot.recordUpdate(input) })
+	bot.safely("Analytics", input.Msg, func() { bot.trackUpdate(input) })
+	bot.safely("Metrics", input.Msg, func() {
+		bot.metrics().UpdateReceived(input.Type())
+		if bot.MainListener != nil {
+			bot.metrics().QueueDepth(len(bot.MainListener))
+		}
+	})
+	bot.reply = input.reply
+	if input.Type() == "message" && input.Msg.ID > 0 {
+		// The handlers only know about messages, the other updates are just marked as processed
+		bot.ProcessAllMsg(input.Msg)
+	}
+	input.reply.finish()
+	bot.MarkProcessed(input.UpdateID)
 }
 
 // ProcessMessages will take care about the highest message ID to get updates in the right way. This will call the MainListener channel with a MessageWithUpdateID
+// Updates already received are skipped, and the offset never goes backwards.
 func (bot *TgBot) ProcessMessages(messages []MessageWithUpdateID) {
+	bot.processUpdates(messages)
+}
+
+// processUpdates returns how many of the messages were new.
+func (bot *TgBot) processUpdates(messages []MessageWithUpdateID) int {
+	fresh := 0
 	for _, msg := range messages {
-		// if int64(msg.UpdateID) > bot.LastUpdateID {
-		// 	// Add lock
-		// 	bot.LastUpdateID = int64(msg.UpdateID)
-		// }
-		atomic.CompareAndSwapInt64(&bot.LastUpdateID, bot.LastUpdateID, int64(msg.UpdateID))
-		if bot.MainListener != nil {
-			bot.MainListener <- msg
+		if !bot.track(msg) {
+			continue
 		}
+		fresh++
+		bot.enqueue(msg, false)
 	}
+	advanceOffset(&bot.LastUpdateID, bot.committedOffset())
+	return fresh
 }
 
 // AddMainListener add the channel as the main listener, this will be called with the messages received.
@@ -153,8 +258,9 @@ func (bot *TgBot) AddMainListener(list chan MessageWithUpdateID) {
 }
 
 // GetMessageChannel create a channel and start the default messages handler, you can use this to build your own server listener (just send the MessageWithUpdateID to that channel)
+// The channel is buffered with the size configured with SetListenerBuffer.
 func (bot *TgBot) GetMessageChannel() chan MessageWithUpdateID {
-	ch := make(chan MessageWithUpdateID)
+	ch := make(chan MessageWithUpdateID, bot.DefaultOptions.ListenerBuffer)
 	go bot.MessagesHandler(ch)
 	return ch
 }
@@ -178,116 +284,102 @@ func (bot *TgBot) StartWithMessagesChannel(ch chan MessageWithUpdateID) {
 }
 
 // Start will start the main process (that use the MainListener channel), it uses getUpdates with longs-polling way and handle the ID
-func (bot *TgBot) Start() {
+// The first PollingOptions, if any, replace the bot polling options.
+func (bot *TgBot) Start(opts ...PollingOptions) {
+	if len(opts) > 0 {
+		bot.Polling = opts[0]
+	}
+
 	if bot.ID == 0 {
-		fmt.Println("No ID, maybe the token is bad.")
+		bot.log().Error("No ID, maybe the token is bad")
 		return
 	}
 
 	if bot.MainListener == nil {
-		fmt.Println("No listener!")
+		bot.log().Error("No listener!")
 		return
 	}
 
-	removedhook := false
+	bot.poll(context.Background(), true)
+}
+
+// poll gets updates until the context is cancelled, the old behaviour of removing the webhook on the first error is kept for Start.
+func (bot *TgBot) poll(ctx context.Context, removeHookOnError bool) {
+	removedhook := !removeHookOnError
 
-	for {
-		updatesList, err := bot.GetUpdates()
+	for ctx.Err() == nil {
+		// The calls of the copy use ctx, so cancelling it aborts the long-polling request
+		pollBot := *bot
+		pollBot.ctx = ctx
+		updatesList, err := pollBot.GetUpdates()
 		if err != nil {
-			fmt.Println(err)
+			if ctx.Err() != nil {
+				return
+			}
+			bot.log().Warn("Error getting updates", "method", "getUpdates", "error", err)
 			if !removedhook {
-				fmt.Println("Removing webhook...")
+				bot.log().Info("Removing webhook...")
 				bot.SetWebhook("")
 				removedhook = true
 			}
+			sleepContext(ctx, pollErrorWait)
 			continue
 		}
-		bot.ProcessMessages(updatesList)
+		if bot.processUpdates(updatesList) == 0 && len(updatesList) > 0 && bot.updates != nil {
+			// Only updates that are still running, wait for them instead of asking again right away
+			bot.updates.wait(time.Second)
+		}
 	}
 }
 
 // ServerStart starts a server that listen for updates, if uri parameter is not empty string, it will try to set the proper webhook
-// The default server uses Martini classic, and listen in POST /<pathl>/token (The token without the :)
-// To listen it runs the Martini.Run() method, that get $HOST and $PORT from the environment, or uses locashost/3000 if not setted.
+// The default server listen in POST /<pathl>/token (The token without the :)
+// It gets $HOST and $PORT from the environment, or uses localhost:3000 if not setted.
+// Use WebhookHandler and ListenAndServe if you want to choose the address, mount it in your own server or stop it.
 func (bot *TgBot) ServerStart(uri string, pathl string) {
 	bot.ServerStartHostPort(uri, pathl, "", "")
 }
 
+// ServerStartHostPort is like ServerStart but listening in host:port.
 func (bot *TgBot) ServerStartHostPort(uri string, pathl string, host string, port string) {
-	if bot.DefaultOptions.RecoverPanic {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("There was some panic: %s\n", r)
-			}
-		}()
-	}
-	tokendiv := strings.Split(bot.Token, ":")
-	if len(tokendiv) != 2 {
+	pathl, err := bot.WebhookPath(pathl)
+	if err != nil {
 		return
 	}
-	pathl = path.Join(pathl, fmt.Sprintf("%s%s", tokendiv[0], tokendiv[1]))
 
 	if uri != "" {
 		puri, err := url.Parse(uri)
 		if err != nil {
-			fmt.Printf("Bad URL %s", uri)
+			bot.log().Error("Bad URL", "url", uri)
 			return
 		}
-		nuri, _ := puri.Parse(pathl)
+		nuri, _ := puri.Parse(strings.TrimPrefix(pathl, "/"))
 
 		res, error := bot.SetWebhook(nuri.String())
 		if error != nil {
-			ec := res.ErrorCode
-			fmt.Printf("Error setting the webhook: \nError code: %d\nDescription: %s\n", &ec, res.Description)
+			bot.log().Error("Error setting the webhook", "method", "setWebhook", "error_code", errorCode(res.ErrorCode), "description", res.Description)
 			return
 		}
 	}
 
-	if bot.MainListener == nil {
-		bot.StartMainListener()
-	}
-
-	m := martini.Classic()
-	m.Post(pathl, binding.Json(MessageWithUpdateID{}), func(params martini.Params, msg MessageWithUpdateID) {
-		if msg.UpdateID > 0 && msg.Msg.ID > 0 {
-			bot.HandleBotan(msg.Msg)
-			bot.MainListener <- msg
-		}
-	})
+	mux := http.NewServeMux()
+	mux.Handle(pathl, bot.WebhookHandler())
 
 	if bot.RelicCfg != nil {
-		gorelic.InitNewrelicAgent(bot.RelicCfg.Token, bot.RelicCfg.Name, false)
-		m.Use(gorelic.Handler)
-	}
-	if host == "" || port == "" {
-		m.Run()
-	} else {
-		m.RunOnAddr(host + ":" + port)
-	}
-}
-
-func (bot TgBot) HandleBotan(msg Message) {
-	if bot.BotanIO != nil {
-		id := msg.Chat.ID
-		name := "other"
-		if msg.Text != nil {
-			name = fmt.Sprintf("text:%s", *msg.Text)
-		}
-		bot.BotanIO.TrackAsync(id, msg, name, func(a botan.Answer, e []error) {})
+		bot.log().Warn("New Relic is not supported anymore, the RelicConfig is ignored")
 	}
+	err = http.ListenAndServe(legacyAddr(host, port), mux)
+	bot.log().Error("The webhook server stopped", "error", err)
 }
 
+// SetRelicConfig ...
+//
+// Deprecated: New Relic is not supported anymore, see RelicConfig.
 func (bot *TgBot) SetRelicConfig(tok string, name string) *TgBot {
 	bot.RelicCfg = &RelicConfig{tok, name}
 	return bot
 }
 
-func (bot *TgBot) SetBotanToken(tok string) *TgBot {
-	t := botan.New(tok)
-	bot.BotanIO = &t
-	return bot
-}
-
 func (bot TgBot) buildPath(action string) string {
 	return fmt.Sprintf(bot.BaseRequestURL, action)
 }
@@ -0,0 +1,247 @@
+package tgbottest
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// Defaults of the fixtures.
+const (
+	DefaultUserID = 1
+	DefaultChatID = 1
+)
+
+// NewUser returns a user with the ID and name.
+func NewUser(id int, firstName string) tgbot.User {
+	return tgbot.User{ID: id, FirstName: firstName}
+}
+
+// MessageBuilder builds a Message. By default it's sent by the user DefaultUserID in its private chat, now, with the message ID 1.
+type MessageBuilder struct {
+	msg tgbot.Message
+}
+
+// NewMessage starts an empty message.
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{tgbot.Message{
+		ID:   1,
+		From: NewUser(DefaultUserID, "User"),
+		Date: int(time.Now().Unix()),
+		Chat: tgbot.UserGroup{ID: DefaultChatID},
+	}}
+}
+
+// TextMessage starts a text message, if it starts with / the command gets its bot_command entity like Telegram does.
+func TextMessage(text string) *MessageBuilder {
+	mb := NewMessage()
+	mb.msg.Text = &text
+	if strings.HasPrefix(text, "/") {
+		command := strings.Fields(text)[0]
+		mb.Entity("bot_command", 0, tgbot.UTF16Len(command))
+	}
+	return mb
+}
+
+// PhotoMessage starts a message with a photo.
+func PhotoMessage(fileID string) *MessageBuilder {
+	mb := NewMessage()
+	mb.msg.Photo = &[]tgbot.PhotoSize{{FileID: fileID, Width: 800, Height: 600}}
+	return mb
+}
+
+// DocumentMessage starts a message with a document.
+func DocumentMessage(fileID string, fileName string) *MessageBuilder {
+	mb := NewMessage()
+	mb.msg.Document = &tgbot.Document{FileID: fileID, FileName: &fileName}
+	return mb
+}
+
+// NewMemberMessage starts the service message of a user joining the group.
+func NewMemberMessage(member tgbot.User) *MessageBuilder {
+	mb := NewMessage().InGroup(-DefaultChatID, "Group")
+	mb.msg.NewChatParticipant = &member
+	return mb
+}
+
+// ID sets the message ID.
+func (mb *MessageBuilder) ID(id int) *MessageBuilder {
+	mb.msg.ID = id
+	return mb
+}
+
+// From sets the sender, and the chat if it's a private one.
+func (mb *MessageBuilder) From(user tgbot.User) *MessageBuilder {
+	mb.msg.From = user
+	if mb.msg.Chat.Title == nil {
+		mb.msg.Chat.ID = user.ID
+	}
+	return mb
+}
+
+// InGroup moves the message to a group.
+func (mb *MessageBuilder) InGroup(chatID int, title string) *MessageBuilder {
+	mb.msg.Chat = tgbot.UserGroup{ID: chatID, Title: &title}
+	return mb
+}
+
+// Date sets when the message was sent.
+func (mb *MessageBuilder) Date(t time.Time) *MessageBuilder {
+	mb.msg.Date = int(t.Unix())
+	return mb
+}
+
+// ReplyTo makes the message a reply.
+func (mb *MessageBuilder) ReplyTo(msg tgbot.Message) *MessageBuilder {
+	mb.msg.ReplyToMessage = &msg
+	return mb
+}
+
+// ForwardedFrom makes the message a forward of a message sent by the user at that time.
+func (mb *MessageBuilder) ForwardedFrom(user tgbot.User, t time.Time) *MessageBuilder {
+	date := int(t.Unix())
+	mb.msg.ForwardFrom = &user
+	mb.msg.ForwardDate = &date
+	return mb
+}
+
+// Caption sets the caption of the media.
+func (mb *MessageBuilder) Caption(caption string) *MessageBuilder {
+	mb.msg.Caption = &caption
+	return mb
+}
+
+// Entity adds an entity to the text, or to the caption if there is no text. The offset and length are in UTF-16 code units.
+func (mb *MessageBuilder) Entity(entityType string, offset int, length int) *MessageBuilder {
+	entity := tgbot.MessageEntity{Type: entityType, Offset: offset, Length: length}
+	target := &mb.msg.Entities
+	if mb.msg.Text == nil {
+		target = &mb.msg.CaptionEntities
+	}
+	if *target == nil {
+		*target = &[]tgbot.MessageEntity{}
+	}
+	**target = append(**target, entity)
+	return mb
+}
+
+// EntityOn adds an entity covering the first appearance of part in the text (or the caption).
+func (mb *MessageBuilder) EntityOn(entityType string, part string) *MessageBuilder {
+	text := ""
+	if mb.msg.Text != nil {
+		text = *mb.msg.Text
+	} else if mb.msg.Caption != nil {
+		text = *mb.msg.Caption
+	}
+	i := strings.Index(text, part)
+	if i < 0 {
+		return mb
+	}
+	return mb.Entity(entityType, tgbot.UTF16Len(text[:i]), tgbot.UTF16Len(part))
+}
+
+// Message returns the message built.
+func (mb *MessageBuilder) Message() tgbot.Message {
+	return mb.msg
+}
+
+// Update returns an update with the message.
+func (mb *MessageBuilder) Update(updateID int) tgbot.Update {
+	return tgbot.Update{Msg: mb.msg, UpdateID: updateID}
+}
+
+// CallbackQueryBuilder builds a CallbackQuery. By default it comes from the user DefaultUserID and has no message.
+type CallbackQueryBuilder struct {
+	cq tgbot.CallbackQuery
+}
+
+// CallbackQuery starts a callback query of a button with the data.
+func CallbackQuery(data string) *CallbackQueryBuilder {
+	return &CallbackQueryBuilder{tgbot.CallbackQuery{
+		ID:           "1",
+		From:         NewUser(DefaultUserID, "User"),
+		ChatInstance: "1",
+		Data:         &data,
+	}}
+}
+
+// ID sets the query ID.
+func (cb *CallbackQueryBuilder) ID(id string) *CallbackQueryBuilder {
+	cb.cq.ID = id
+	return cb
+}
+
+// From sets the user that pressed the button.
+func (cb *CallbackQueryBuilder) From(user tgbot.User) *CallbackQueryBuilder {
+	cb.cq.From = user
+	return cb
+}
+
+// OnMessage sets the message of the button.
+func (cb *CallbackQueryBuilder) OnMessage(msg tgbot.Message) *CallbackQueryBuilder {
+	cb.cq.Message = &msg
+	cb.cq.ChatInstance = strconv.Itoa(msg.Chat.ID)
+	return cb
+}
+
+// CallbackQuery returns the query built.
+func (cb *CallbackQueryBuilder) CallbackQuery() tgbot.CallbackQuery {
+	return cb.cq
+}
+
+// Update returns an update with the query.
+func (cb *CallbackQueryBuilder) Update(updateID int) tgbot.Update {
+	cq := cb.cq
+	return tgbot.Update{UpdateID: updateID, CallbackQuery: &cq}
+}
+
+// InlineQueryBuilder builds an InlineQuery. By default it comes from the user DefaultUserID.
+type InlineQueryBuilder struct {
+	iq tgbot.InlineQuery
+}
+
+// InlineQuery starts an inline query with the text.
+func InlineQuery(query string) *InlineQueryBuilder {
+	return &InlineQueryBuilder{tgbot.InlineQuery{
+		ID:    "1",
+		From:  NewUser(DefaultUserID, "User"),
+		Query: query,
+	}}
+}
+
+// ID sets the query ID.
+func (ib *InlineQueryBuilder) ID(id string) *InlineQueryBuilder {
+	ib.iq.ID = id
+	return ib
+}
+
+// From sets the user that wrote the query.
+func (ib *InlineQueryBuilder) From(user tgbot.User) *InlineQueryBuilder {
+	ib.iq.From = user
+	return ib
+}
+
+// Offset sets the offset of the results asked.
+func (ib *InlineQueryBuilder) Offset(offset string) *InlineQueryBuilder {
+	ib.iq.Offset = offset
+	return ib
+}
+
+// Location sets the location of the user.
+func (ib *InlineQueryBuilder) Location(latitude float64, longitude float64) *InlineQueryBuilder {
+	ib.iq.Location = &tgbot.Location{Longitude: longitude, Latitude: latitude}
+	return ib
+}
+
+// InlineQuery returns the query built.
+func (ib *InlineQueryBuilder) InlineQuery() tgbot.InlineQuery {
+	return ib.iq
+}
+
+// Update returns an update with the query.
+func (ib *InlineQueryBuilder) Update(updateID int) tgbot.Update {
+	iq := ib.iq
+	return tgbot.Update{UpdateID: updateID, InlineQuery: &iq}
+}
@@ -0,0 +1,84 @@
+package tgbottest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextMessageCommand(t *testing.T) {
+	msg := TextMessage("/start@test_bot now").Message()
+	if msg.Entities == nil || len(*msg.Entities) != 1 {
+		t.Fatalf("entities = %v, want the bot_command", msg.Entities)
+	}
+	if e := (*msg.Entities)[0]; e.Type != "bot_command" || e.Offset != 0 || e.Length != 15 {
+		t.Errorf("entity = %+v", e)
+	}
+	command, botname, ok := msg.Command()
+	if !ok || command != "start" || botname != "test_bot" {
+		t.Errorf("Command() = %q, %q, %v", command, botname, ok)
+	}
+
+	if msg := TextMessage("hello").Message(); msg.Entities != nil {
+		t.Errorf("the text without command has entities: %v", *msg.Entities)
+	}
+}
+
+func TestEntityOnCountsUTF16(t *testing.T) {
+	// The emoji takes two UTF-16 units, and the é one
+	msg := TextMessage("😀 café #go").EntityOn("hashtag", "#go").Message()
+	e := (*msg.Entities)[0]
+	if e.Offset != 8 || e.Length != 3 {
+		t.Errorf("entity = %+v, want offset 8 and length 3", e)
+	}
+	if got := msg.EntityTexts("hashtag"); !reflect.DeepEqual(got, []string{"#go"}) {
+		t.Errorf("EntityTexts = %q", got)
+	}
+}
+
+func TestEntityOnCaption(t *testing.T) {
+	msg := PhotoMessage("AgAD").Caption("look @ann").EntityOn("mention", "@ann").Message()
+	if msg.Entities != nil || msg.CaptionEntities == nil {
+		t.Fatalf("the entity is not in the caption: %+v", msg)
+	}
+	if got := msg.EntityTexts("mention"); !reflect.DeepEqual(got, []string{"@ann"}) {
+		t.Errorf("EntityTexts = %q", got)
+	}
+}
+
+func TestMessageBuilderChats(t *testing.T) {
+	ann := NewUser(7, "Ann")
+	private := TextMessage("hi").From(ann).Message()
+	if private.Chat.ID != 7 {
+		t.Errorf("private chat = %d, want the user 7", private.Chat.ID)
+	}
+
+	group := TextMessage("hi").InGroup(-100, "Group").From(ann).ReplyTo(private).Message()
+	if group.Chat.ID != -100 || group.From.ID != 7 {
+		t.Errorf("group message in %d from %d", group.Chat.ID, group.From.ID)
+	}
+	if group.ReplyToMessage == nil || *group.ReplyToMessage.Text != "hi" {
+		t.Errorf("reply to = %+v", group.ReplyToMessage)
+	}
+}
+
+func TestQueryBuilders(t *testing.T) {
+	msg := TextMessage("pick").InGroup(-100, "Group").Message()
+	cq := CallbackQuery("yes").From(NewUser(7, "Ann")).OnMessage(msg).Update(3)
+	if cq.UpdateID != 3 || cq.CallbackQuery == nil || *cq.CallbackQuery.Data != "yes" {
+		t.Fatalf("callback update = %+v", cq)
+	}
+	if cq.CallbackQuery.From.ID != 7 || cq.CallbackQuery.Message.Chat.ID != -100 || cq.CallbackQuery.ChatInstance != "-100" {
+		t.Errorf("callback query = %+v", cq.CallbackQuery)
+	}
+
+	iq := InlineQuery("cats").Offset("10").Location(1, 2).Update(4)
+	if iq.InlineQuery == nil || iq.InlineQuery.Query != "cats" || iq.InlineQuery.Offset != "10" {
+		t.Fatalf("inline update = %+v", iq)
+	}
+	if loc := iq.InlineQuery.Location; loc == nil || loc.Latitude != 1 || loc.Longitude != 2 {
+		t.Errorf("location = %+v", loc)
+	}
+	if got := iq.Type(); got != "inline_query" {
+		t.Errorf("Type() = %q, want inline_query", got)
+	}
+}
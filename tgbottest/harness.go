@@ -0,0 +1,165 @@
+package tgbottest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// Harness runs the handlers of a bot without the network, the updates are given straight to ProcessAllMsg
+// and the API calls are answered in memory by the fake API.
+type Harness struct {
+	Bot *tgbot.TgBot
+	*fakeAPI
+
+	lastChat int
+	mark     int // Calls done before the last update
+}
+
+// NewHarness creates a bot (without calling getMe) whose calls never leave the process.
+func NewHarness() *Harness {
+	api := newFakeAPI(DefaultToken)
+	bot := tgbot.NewWithUser(api.Token, api.Me)
+	bot.SetHTTPClient(&http.Client{Transport: memoryTransport{api}})
+	return &Harness{Bot: bot, fakeAPI: api}
+}
+
+// memoryTransport answers the requests with the fake API in the same goroutine.
+type memoryTransport struct {
+	api *fakeAPI
+}
+
+func (mt memoryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	mt.api.serve(rec, r)
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	return rec.Result(), nil
+}
+
+// Feed gives the update to the handlers, it returns when they finish.
+func (h *Harness) Feed(update tgbot.MessageWithUpdateID) *Harness {
+	h.mu.Lock()
+	h.mark = len(h.calls)
+	h.lastChat = update.Msg.Chat.ID
+	h.mu.Unlock()
+	if update.Type() == "message" {
+		h.Bot.ProcessAllMsg(update.Msg)
+	}
+	return h
+}
+
+// FeedMessage gives the message to the handlers in a new update.
+func (h *Harness) FeedMessage(msg tgbot.Message) *Harness {
+	h.mu.Lock()
+	h.updateID++
+	id := h.updateID
+	if msg.ID == 0 {
+		h.messageID++
+		msg.ID = h.messageID
+	}
+	h.mu.Unlock()
+	if msg.Date == 0 {
+		msg.Date = int(time.Now().Unix())
+	}
+	return h.Feed(tgbot.MessageWithUpdateID{Msg: msg, UpdateID: id})
+}
+
+// Text gives to the handlers a text message from the user in its private chat, with the bot_command entity if it's a command.
+func (h *Harness) Text(userID int, text string) *Harness {
+	return h.FeedMessage(TextMessage(text).From(NewUser(userID, "User")).ID(0).Message())
+}
+
+// Expect starts the assertions about the calls done by the handlers of the last update.
+func (h *Harness) Expect(t testing.TB) *Expectation {
+	return &Expectation{t: t, calls: h.lastCalls(), chat: h.lastChat}
+}
+
+// lastCalls returns the calls done by the handlers of the last update.
+func (h *Harness) lastCalls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Call{}, h.calls[h.mark:]...)
+}
+
+// Expectation checks the calls done by the handlers, every failed check is reported to the test with Errorf.
+type Expectation struct {
+	t     testing.TB
+	calls []Call
+	chat  int
+}
+
+// RepliedWithText checks that a message matching the pattern was sent to the chat of the update.
+func (e *Expectation) RepliedWithText(pattern string) *Expectation {
+	e.t.Helper()
+	return e.SentText(e.chat, pattern)
+}
+
+// SentText checks that a message matching the pattern was sent to the chat.
+func (e *Expectation) SentText(chatID int, pattern string) *Expectation {
+	e.t.Helper()
+	re := regexp.MustCompile(pattern)
+	for _, c := range e.calls {
+		if c.Method == "sendMessage" && chatOf(c) == chatID && re.MatchString(c.Params["text"]) {
+			return e
+		}
+	}
+	e.t.Errorf("no message matching %q was sent to the chat %d, the calls were:\n%s", pattern, chatID, describe(e.calls))
+	return e
+}
+
+// SentPhotoTo checks that a photo was sent to the chat.
+func (e *Expectation) SentPhotoTo(chatID int) *Expectation {
+	e.t.Helper()
+	return e.Sent("sendPhoto", chatID)
+}
+
+// Sent checks that the method was called for the chat.
+func (e *Expectation) Sent(method string, chatID int) *Expectation {
+	e.t.Helper()
+	for _, c := range e.calls {
+		if c.Method == method && chatOf(c) == chatID {
+			return e
+		}
+	}
+	e.t.Errorf("%s was not called for the chat %d, the calls were:\n%s", method, chatID, describe(e.calls))
+	return e
+}
+
+// NothingSent checks that the handlers didn't call the API.
+func (e *Expectation) NothingSent() *Expectation {
+	e.t.Helper()
+	if len(e.calls) > 0 {
+		e.t.Errorf("expected no calls, the calls were:\n%s", describe(e.calls))
+	}
+	return e
+}
+
+// Calls returns the calls being checked.
+func (e *Expectation) Calls() []Call {
+	return e.calls
+}
+
+func chatOf(c Call) int {
+	id, _ := strconv.Atoi(c.Params["chat_id"])
+	return id
+}
+
+func describe(calls []Call) string {
+	if len(calls) == 0 {
+		return "\t(none)"
+	}
+	lines := make([]string, len(calls))
+	for i, c := range calls {
+		lines[i] = fmt.Sprintf("\t%s %v", c.Method, c.Params)
+	}
+	return strings.Join(lines, "\n")
+}
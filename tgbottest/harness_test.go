@@ -0,0 +1,68 @@
+package tgbottest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// failures is a testing.TB that keeps the errors instead of failing the test.
+type failures struct {
+	testing.TB
+	errors []string
+}
+
+func (f *failures) Helper() {}
+
+func (f *failures) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func echoHarness() *Harness {
+	h := NewHarness()
+	h.Bot.CommandFn(`echo (.+)`, func(bot tgbot.TgBot, msg tgbot.Message, args []string, kw map[string]string) *string {
+		return &args[1]
+	})
+	return h
+}
+
+func TestHarnessReplies(t *testing.T) {
+	h := echoHarness()
+	h.Text(7, "/echo hello").Expect(t).RepliedWithText("^hello$").SentText(7, "hello")
+}
+
+func TestHarnessNothingSent(t *testing.T) {
+	h := echoHarness()
+	h.Text(7, "just talking").Expect(t).NothingSent()
+}
+
+func TestHarnessOnlyTheLastUpdate(t *testing.T) {
+	h := echoHarness()
+	h.Text(7, "/echo one")
+	calls := h.Text(7, "/echo two").Expect(t).Calls()
+	if len(calls) != 1 || calls[0].Params["text"] != "two" {
+		t.Errorf("calls of the last update = %v", calls)
+	}
+}
+
+func TestHarnessReportsFailures(t *testing.T) {
+	h := echoHarness()
+	f := &failures{TB: t}
+	h.Text(7, "/echo hello").Expect(f).RepliedWithText("bye").SentPhotoTo(7).NothingSent()
+	if len(f.errors) != 3 {
+		t.Errorf("%d errors reported, want 3: %q", len(f.errors), f.errors)
+	}
+}
+
+func TestHarnessCommandsOfOtherBots(t *testing.T) {
+	h := echoHarness()
+	h.Text(7, "/echo@other_bot hello").Expect(t).NothingSent()
+	h.Text(7, "/echo@test_bot hello").Expect(t).RepliedWithText("^hello$")
+}
+
+func TestHarnessFeedGroupMessage(t *testing.T) {
+	h := echoHarness()
+	msg := TextMessage("/echo hi").From(NewUser(7, "Ann")).InGroup(-100, "Group").Message()
+	h.FeedMessage(msg).Expect(t).SentText(-100, "^hi$")
+}
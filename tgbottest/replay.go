@@ -0,0 +1,105 @@
+package tgbottest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// ReplayDiff is an update of a recording whose handlers didn't do the recorded calls.
+type ReplayDiff struct {
+	UpdateID int
+	Recorded []tgbot.RecordEntry
+	Got      []Call
+}
+
+func (rd ReplayDiff) String() string {
+	lines := []string{fmt.Sprintf("update %d:", rd.UpdateID), "  recorded:"}
+	for _, e := range rd.Recorded {
+		lines = append(lines, fmt.Sprintf("\t%s %v %v", e.Method, e.Params, e.Files))
+	}
+	lines = append(lines, "  got:")
+	for _, c := range rd.Got {
+		files := map[string]string{}
+		for field, f := range c.Files {
+			files[field] = f.Name
+		}
+		lines = append(lines, fmt.Sprintf("\t%s %v %v", c.Method, c.Params, files))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Replay feeds the updates of a recording (made with tgbot.Recorder) to the handlers of the harness, in order,
+// and returns the updates whose calls are not the recorded ones. Give the harness bot the username of the recorded bot
+// if the commands are written with it.
+func Replay(h *Harness, r io.Reader) ([]ReplayDiff, error) {
+	entries, err := tgbot.ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := map[int][]tgbot.RecordEntry{}
+	for _, e := range entries {
+		if e.Kind == "call" && e.UpdateID != 0 {
+			recorded[e.UpdateID] = append(recorded[e.UpdateID], e)
+		}
+	}
+
+	diffs := []ReplayDiff{}
+	for _, e := range entries {
+		if e.Kind != "update" || e.Update == nil {
+			continue
+		}
+		got := h.Feed(*e.Update).lastCalls()
+		if !sameCalls(recorded[e.UpdateID], got) {
+			diffs = append(diffs, ReplayDiff{e.UpdateID, recorded[e.UpdateID], got})
+		}
+	}
+	return diffs, nil
+}
+
+// ReplayFile is Replay reading the recording from the file.
+func ReplayFile(h *Harness, name string) ([]ReplayDiff, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Replay(h, f)
+}
+
+func sameCalls(recorded []tgbot.RecordEntry, got []Call) bool {
+	if len(recorded) != len(got) {
+		return false
+	}
+	for i, e := range recorded {
+		if e.Method != got[i].Method {
+			return false
+		}
+		params := e.Params
+		if params == nil {
+			params = map[string]string{}
+		}
+		if !reflect.DeepEqual(params, got[i].Params) || !sameFiles(e.Files, got[i].Files) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameFiles compares the files recorded by their names, the content is not recorded.
+func sameFiles(recorded map[string]string, got map[string]UploadedFile) bool {
+	if len(recorded) != len(got) {
+		return false
+	}
+	for field, name := range recorded {
+		if f, ok := got[field]; !ok || f.Name != name {
+			return false
+		}
+	}
+	return true
+}
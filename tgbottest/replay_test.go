@@ -0,0 +1,116 @@
+package tgbottest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// greeter greets the user by name with a photo, done is called when it finishes.
+func greeter(photo string, greeting string, done func()) func(tgbot.TgBot, tgbot.Message, []string, map[string]string) *string {
+	return func(bot tgbot.TgBot, msg tgbot.Message, args []string, kw map[string]string) *string {
+		bot.Send(msg.Chat.ID).Photo(photo).End()
+		bot.Send(msg.Chat.ID).Text(greeting + " " + msg.From.FirstName).End()
+		done()
+		return nil
+	}
+}
+
+// record runs the greeter with the fake server, recording the traffic with the names redacted.
+func record(t *testing.T, photo string) *bytes.Buffer {
+	srv, bot := newTestBot(t)
+	var buf bytes.Buffer
+	rec := tgbot.NewRecorder(&buf)
+	rec.RedactPII = true
+	bot.SetRecorder(rec)
+
+	finished := make(chan struct{})
+	bot.CommandFn(`hello`, greeter(photo, "Hi", func() { close(finished) }))
+	bot.StartMainListener()
+
+	username := "alice"
+	srv.AddUpdate(TextMessage("/hello").From(tgbot.User{ID: 555, FirstName: "Alice", Username: &username}).Message())
+	updates, err := bot.GetUpdates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.ProcessMessages(updates)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler didn't finish")
+	}
+	return &buf
+}
+
+func photoFile(t *testing.T) string {
+	photo := filepath.Join(t.TempDir(), "cat.jpg")
+	if err := ioutil.WriteFile(photo, []byte("meow"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return photo
+}
+
+func TestRecordingRedactsTheNames(t *testing.T) {
+	buf := record(t, photoFile(t))
+	entries, err := tgbot.ReadRecording(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Alice")) || bytes.Contains(buf.Bytes(), []byte("alice")) {
+		t.Errorf("the recording has the name of the user:\n%s", buf)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(DefaultToken)) {
+		t.Errorf("the recording has the token:\n%s", buf)
+	}
+
+	var photo *tgbot.RecordEntry
+	for i, e := range entries {
+		if e.Method == "sendPhoto" {
+			photo = &entries[i]
+		}
+	}
+	if photo == nil {
+		t.Fatalf("sendPhoto was not recorded:\n%s", buf)
+	}
+	if _, ok := photo.Params["photo"]; ok || photo.Files["photo"] != "cat.jpg" {
+		t.Errorf("the upload is not recorded apart: params %v, files %v", photo.Params, photo.Files)
+	}
+}
+
+func TestReplaySameCalls(t *testing.T) {
+	photo := photoFile(t)
+	buf := record(t, photo)
+
+	h := NewHarness()
+	h.Bot.CommandFn(`hello`, greeter(photo, "Hi", func() {}))
+	diffs, err := Replay(h, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		t.Errorf("the replay found a difference:\n%s", d)
+	}
+}
+
+func TestReplayChangedCalls(t *testing.T) {
+	photo := photoFile(t)
+	buf := record(t, photo)
+
+	h := NewHarness()
+	h.Bot.CommandFn(`hello`, greeter(photo, "Bye", func() {}))
+	diffs, err := Replay(h, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("%d differences, want 1", len(diffs))
+	}
+	if got := diffs[0].Got; len(got) != 2 || got[1].Params["text"] != "Bye User" {
+		t.Errorf("calls of the replay = %v", got)
+	}
+}
@@ -0,0 +1,533 @@
+// Package tgbottest has a fake Telegram Bot API server to test the bots without the network.
+package tgbottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// Call is a request that the bot did to the fake server.
+type Call struct {
+	Method string
+	Params map[string]string // The JSON values that are not strings are kept encoded
+	Files  map[string]UploadedFile
+	Time   time.Time
+}
+
+// UploadedFile is a file sent in a multipart request.
+type UploadedFile struct {
+	Name string
+	Data []byte
+}
+
+type apiError struct {
+	code        int
+	description string
+}
+
+// Server is a fake Bot API in an httptest.Server.
+type Server struct {
+	*httptest.Server
+	*fakeAPI
+}
+
+// fakeAPI answers getMe, getUpdates, the webhook methods, getFile and file downloads, and the send*, forwardMessage and edit* methods
+// with a new message (sendMediaGroup with one per media, copyMessage with its ID, and the inline edits with true),
+// every other method answers true. All the calls are recorded.
+type fakeAPI struct {
+	Token string
+	Me    tgbot.User
+
+	mu        sync.Mutex
+	calls     []Call
+	updates   []tgbot.MessageWithUpdateID
+	updateID  int
+	messageID int
+	fileID    int
+	files     map[string]UploadedFile
+	webhook   tgbot.WebhookInfo
+	failures  map[string][]apiError
+	changed   chan struct{} // Closed and replaced when there are new updates or calls
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeAPI(token string) *fakeAPI {
+	id, _ := strconv.Atoi(strings.SplitN(token, ":", 2)[0])
+	username := "test_bot"
+	return &fakeAPI{
+		Token:    token,
+		Me:       tgbot.User{ID: id, FirstName: "Test", Username: &username},
+		files:    map[string]UploadedFile{},
+		failures: map[string][]apiError{},
+		changed:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+// DefaultToken is the token used by NewServer.
+const DefaultToken = "123456:test-token"
+
+// NewServer starts a fake server for a bot called test_bot with the DefaultToken.
+func NewServer() *Server {
+	return NewServerWithToken(DefaultToken)
+}
+
+// NewServerWithToken starts a fake server that only accepts the token, the bot ID is the part before the colon.
+func NewServerWithToken(token string) *Server {
+	api := newFakeAPI(token)
+	return &Server{httptest.NewServer(http.HandlerFunc(api.serve)), api}
+}
+
+// NewBot creates a bot that talks with this server.
+func (s *Server) NewBot() (*tgbot.TgBot, error) {
+	return tgbot.NewWithURL(s.Token, s.URL)
+}
+
+// Close stops the server, the getUpdates that are waiting return right away.
+func (s *Server) Close() {
+	s.stop()
+	s.Server.Close()
+}
+
+func (s *fakeAPI) stop() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+}
+
+// AddUpdate queues the message as a new update for getUpdates, and returns its update ID.
+func (s *fakeAPI) AddUpdate(msg tgbot.Message) int {
+	s.mu.Lock()
+	if msg.ID == 0 {
+		s.messageID++
+		msg.ID = s.messageID
+	}
+	s.mu.Unlock()
+	if msg.Date == 0 {
+		msg.Date = int(time.Now().Unix())
+	}
+	return s.AddRawUpdate(tgbot.Update{Msg: msg})
+}
+
+// AddRawUpdate queues the update (built with the fixtures, for example) for getUpdates, the update ID is replaced with the next one.
+func (s *fakeAPI) AddRawUpdate(update tgbot.Update) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateID++
+	update.UpdateID = s.updateID
+	s.updates = append(s.updates, update)
+	s.notify()
+	return s.updateID
+}
+
+// AddText queues a text message from the user in its private chat.
+func (s *fakeAPI) AddText(userID int, text string) int {
+	return s.AddUpdate(tgbot.Message{
+		From: tgbot.User{ID: userID, FirstName: "User"},
+		Chat: tgbot.UserGroup{ID: userID},
+		Text: &text,
+	})
+}
+
+// AddFile makes the file available for getFile and download, returns its file ID.
+func (s *fakeAPI) AddFile(name string, data []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addFile(name, data)
+}
+
+// FailNext makes the next call to the method fail with the error.
+func (s *fakeAPI) FailNext(method string, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], apiError{code, description})
+}
+
+// Calls returns all the calls received, in order.
+func (s *fakeAPI) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// CallsTo returns the calls received to the method.
+func (s *fakeAPI) CallsTo(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := []Call{}
+	for _, c := range s.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// WaitCalls waits until the method has been called n times, and returns the calls, false if the timeout expires first.
+func (s *fakeAPI) WaitCalls(method string, n int, timeout time.Duration) ([]Call, bool) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		if calls := s.CallsTo(method); len(calls) >= n {
+			return calls, true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return s.CallsTo(method), false
+		}
+	}
+}
+
+// Webhook returns the webhook set by the bot.
+func (s *fakeAPI) Webhook() tgbot.WebhookInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.webhook
+	info.PendingUpdateCount = len(s.updates)
+	return info
+}
+
+// Reset forgets the calls and the pending updates.
+func (s *fakeAPI) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+	s.updates = nil
+}
+
+func (s *fakeAPI) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *fakeAPI) addFile(name string, data []byte) string {
+	s.fileID++
+	id := fmt.Sprintf("file%d", s.fileID)
+	s.files[id] = UploadedFile{name, data}
+	return id
+}
+
+func (s *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) == 3 && parts[0] == "file" {
+		s.serveFile(w, parts[1], parts[2])
+		return
+	}
+	if len(parts) != 2 || parts[0] != "bot"+s.Token {
+		writeError(w, apiError{401, "Unauthorized"})
+		return
+	}
+
+	call, err := readCall(r, parts[1])
+	if err != nil {
+		writeError(w, apiError{400, "Bad Request: " + err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.notify()
+	if failures := s.failures[call.Method]; len(failures) > 0 {
+		s.failures[call.Method] = failures[1:]
+		s.mu.Unlock()
+		writeError(w, failures[0])
+		return
+	}
+	s.mu.Unlock()
+
+	if call.Method == "getUpdates" {
+		s.getUpdates(w, r, call)
+		return
+	}
+
+	s.mu.Lock()
+	result, apierr := s.answer(call)
+	s.mu.Unlock()
+	if apierr != nil {
+		writeError(w, *apierr)
+		return
+	}
+	writeResult(w, result)
+}
+
+func (s *fakeAPI) serveFile(w http.ResponseWriter, tokenpart string, filepath string) {
+	if tokenpart != "bot"+s.Token {
+		http.NotFound(w, nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, f := range s.files {
+		if filePath(id, f) == filepath {
+			w.Write(f.Data)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// getUpdates answers the updates after the offset, waiting for them up to the timeout.
+func (s *fakeAPI) getUpdates(w http.ResponseWriter, r *http.Request, call Call) {
+	offset, _ := strconv.Atoi(call.Params["offset"])
+	limit, _ := strconv.Atoi(call.Params["limit"])
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	timeout, _ := strconv.Atoi(call.Params["timeout"])
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		s.mu.Lock()
+		if s.webhook.URL != "" {
+			s.mu.Unlock()
+			writeError(w, apiError{409, "Conflict: can't use getUpdates method while webhook is active; use deleteWebhook to delete the webhook first"})
+			return
+		}
+		// Like Telegram, asking with an offset confirms the previous updates
+		pending := []tgbot.MessageWithUpdateID{}
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		s.updates = pending
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(pending) > 0 || timeout <= 0 {
+			if len(pending) > limit {
+				pending = pending[:limit]
+			}
+			writeResult(w, pending)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			timeout = 0
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			timeout = 0
+		}
+	}
+}
+
+func (s *fakeAPI) answer(call Call) (interface{}, *apiError) {
+	p := call.Params
+	switch {
+	case call.Method == "getMe":
+		return s.Me, nil
+	case call.Method == "setWebhook":
+		s.webhook.URL = p["url"]
+		_, s.webhook.HasCustomCertificate = call.Files["certificate"]
+		return true, nil
+	case call.Method == "deleteWebhook":
+		s.webhook = tgbot.WebhookInfo{}
+		if p["drop_pending_updates"] == "true" {
+			s.updates = nil
+		}
+		return true, nil
+	case call.Method == "getWebhookInfo":
+		info := s.webhook
+		info.PendingUpdateCount = len(s.updates)
+		return info, nil
+	case call.Method == "getFile":
+		f, ok := s.files[p["file_id"]]
+		if !ok {
+			return nil, &apiError{400, "Bad Request: invalid file_id"}
+		}
+		return tgbot.File{ID: p["file_id"], Size: len(f.Data), Path: filePath(p["file_id"], f)}, nil
+	case call.Method == "getUserProfilePhotos":
+		return tgbot.UserProfilePhotos{}, nil
+	case call.Method == "sendChatAction":
+		return true, nil
+	case call.Method == "sendMediaGroup":
+		return s.mediaGroup(call)
+	case call.Method == "copyMessage":
+		s.messageID++
+		return map[string]int{"message_id": s.messageID}, nil
+	case strings.HasPrefix(call.Method, "edit") && p["inline_message_id"] != "":
+		// The edits of inline messages don't return the message
+		return true, nil
+	case strings.HasPrefix(call.Method, "send"), strings.HasPrefix(call.Method, "edit"), call.Method == "forwardMessage":
+		return s.message(call), nil
+	}
+	return true, nil
+}
+
+// message builds the message that the bot sent with the call.
+func (s *fakeAPI) message(call Call) tgbot.Message {
+	p := call.Params
+	msg := s.newMessage(call)
+	if text, ok := p["text"]; ok {
+		msg.Text = &text
+	}
+	if entities, ok := p["entities"]; ok {
+		json.Unmarshal([]byte(entities), &msg.Entities)
+	}
+	if caption, ok := p["caption"]; ok {
+		msg.Caption = &caption
+	}
+
+	kind := strings.ToLower(strings.TrimPrefix(call.Method, "send"))
+	fileID, ok := p[kind]
+	if f, uploaded := call.Files[kind]; uploaded {
+		fileID, ok = s.addFile(f.Name, f.Data), true
+	}
+	if ok {
+		setMedia(&msg, kind, fileID)
+	}
+	return msg
+}
+
+// mediaGroup builds the messages of sendMediaGroup, the media are file IDs or uploads named with attach://<field>.
+func (s *fakeAPI) mediaGroup(call Call) (interface{}, *apiError) {
+	var media []struct {
+		Type    string  `json:"type"`
+		Media   string  `json:"media"`
+		Caption *string `json:"caption"`
+	}
+	if err := json.Unmarshal([]byte(call.Params["media"]), &media); err != nil || len(media) == 0 {
+		return nil, &apiError{400, "Bad Request: can't parse media JSON object"}
+	}
+	msgs := []tgbot.Message{}
+	for _, m := range media {
+		fileID := m.Media
+		if field := strings.TrimPrefix(m.Media, "attach://"); field != m.Media {
+			f, ok := call.Files[field]
+			if !ok {
+				return nil, &apiError{400, "Bad Request: wrong file identifier/HTTP URL specified"}
+			}
+			fileID = s.addFile(f.Name, f.Data)
+		}
+		msg := s.newMessage(call)
+		msg.Caption = m.Caption
+		setMedia(&msg, m.Type, fileID)
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// newMessage builds a message of the bot in the chat of the call, with a new ID or the ID edited.
+func (s *fakeAPI) newMessage(call Call) tgbot.Message {
+	p := call.Params
+	chatID, _ := strconv.Atoi(p["chat_id"])
+	msg := tgbot.Message{
+		From: s.Me,
+		Date: int(time.Now().Unix()),
+		Chat: tgbot.UserGroup{ID: chatID},
+	}
+	if id, err := strconv.Atoi(p["message_id"]); err == nil && strings.HasPrefix(call.Method, "edit") {
+		msg.ID = id
+	} else {
+		s.messageID++
+		msg.ID = s.messageID
+	}
+	return msg
+}
+
+// setMedia puts the file in the field of the message of its kind, like "photo" or "video".
+func setMedia(msg *tgbot.Message, kind string, fileID string) {
+	switch kind {
+	case "photo":
+		msg.Photo = &[]tgbot.PhotoSize{{FileID: fileID}}
+	case "audio":
+		msg.Audio = &tgbot.Audio{FileID: fileID}
+	case "voice":
+		msg.Voice = &tgbot.Voice{FileID: fileID}
+	case "document":
+		msg.Document = &tgbot.Document{FileID: fileID}
+	case "sticker":
+		msg.Sticker = &tgbot.Sticker{FileID: fileID}
+	case "video":
+		msg.Video = &tgbot.Video{FileID: fileID}
+	}
+}
+
+func filePath(id string, f UploadedFile) string {
+	return path.Join("files", id, path.Base(f.Name))
+}
+
+// readCall reads the parameters of the request, in the query, a form, a multipart form or JSON.
+func readCall(r *http.Request, method string) (Call, error) {
+	call := Call{
+		Method: method,
+		Params: map[string]string{},
+		Files:  map[string]UploadedFile{},
+		Time:   time.Now(),
+	}
+	for k, v := range r.URL.Query() {
+		call.Params[k] = v[0]
+	}
+
+	ctype := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ctype, "application/json"):
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return call, err
+		}
+		for k, v := range body {
+			if str, ok := v.(string); ok {
+				call.Params[k] = str
+				continue
+			}
+			encoded, _ := json.Marshal(v)
+			call.Params[k] = string(encoded)
+		}
+	case strings.HasPrefix(ctype, "multipart/form-data"):
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return call, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			call.Params[k] = v[0]
+		}
+		for k, fhs := range r.MultipartForm.File {
+			f, err := fhs[0].Open()
+			if err != nil {
+				return call, err
+			}
+			data, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return call, err
+			}
+			call.Files[k] = UploadedFile{fhs[0].Filename, data}
+		}
+	case r.Method == http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			return call, err
+		}
+		for k, v := range r.PostForm {
+			call.Params[k] = v[0]
+		}
+	}
+	return call, nil
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, e apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": e.code, "description": e.description})
+}
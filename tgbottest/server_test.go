@@ -0,0 +1,192 @@
+package tgbottest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+func newTestBot(t *testing.T) (*Server, *tgbot.TgBot) {
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	bot, err := srv.NewBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, bot
+}
+
+func TestServerGetMe(t *testing.T) {
+	_, bot := newTestBot(t)
+	if bot.ID != 123456 || bot.Username != "test_bot" {
+		t.Errorf("bot = %d @%s, want 123456 @test_bot", bot.ID, bot.Username)
+	}
+}
+
+func TestServerRejectsOtherTokens(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	if _, err := tgbot.NewWithURL("1:other", srv.URL); err == nil {
+		t.Error("the bot with another token was accepted")
+	}
+}
+
+func TestServerRecordsTheCalls(t *testing.T) {
+	srv, bot := newTestBot(t)
+	res := bot.SendMessage(42, "hello", nil, nil, nil, nil)
+	if !res.Ok || res.Result == nil {
+		t.Fatalf("sendMessage failed: %+v", res)
+	}
+	if res.Result.Text == nil || *res.Result.Text != "hello" || res.Result.Chat.ID != 42 {
+		t.Errorf("the message answered is not the one sent: %+v", res.Result)
+	}
+
+	calls := srv.CallsTo("sendMessage")
+	if len(calls) != 1 {
+		t.Fatalf("%d calls to sendMessage, want 1", len(calls))
+	}
+	if calls[0].Params["chat_id"] != "42" || calls[0].Params["text"] != "hello" {
+		t.Errorf("params = %v", calls[0].Params)
+	}
+}
+
+func TestServerFailNext(t *testing.T) {
+	srv, bot := newTestBot(t)
+	srv.FailNext("sendMessage", 403, "Forbidden: bot was blocked by the user")
+
+	res := bot.SendMessage(42, "hello", nil, nil, nil, nil)
+	if res.Ok || res.ErrorCode == nil || *res.ErrorCode != 403 {
+		t.Errorf("the call didn't fail with 403: %+v", res)
+	}
+	if res := bot.SendMessage(42, "hello", nil, nil, nil, nil); !res.Ok {
+		t.Errorf("only the next call should fail: %+v", res)
+	}
+}
+
+func TestServerGetUpdates(t *testing.T) {
+	srv, bot := newTestBot(t)
+	first := srv.AddText(7, "/start")
+	srv.AddText(7, "hi")
+
+	updates, err := bot.GetUpdates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 || updates[0].UpdateID != first {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if updates[1].Msg.Text == nil || *updates[1].Msg.Text != "hi" || updates[1].Msg.From.ID != 7 {
+		t.Errorf("second update = %+v", updates[1].Msg)
+	}
+}
+
+func TestServerMediaGroup(t *testing.T) {
+	srv, bot := newTestBot(t)
+	params := struct {
+		ChatID int    `json:"chat_id"`
+		Media  string `json:"media"`
+	}{42, `[{"type":"photo","media":"attach://cat","caption":"cat"},{"type":"photo","media":"AgADexisting"}]`}
+	files := tgbot.InputFile{Field: "cat", File: tgbot.ReaderSender{Read: strings.NewReader("meow"), Name: "cat.jpg"}}
+
+	msgs, err := tgbot.Call[[]tgbot.Message](context.Background(), *bot, "sendMediaGroup", tgbot.WithFiles(params, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages, want 2", len(msgs))
+	}
+	if msgs[0].Photo == nil || msgs[0].Caption == nil || *msgs[0].Caption != "cat" {
+		t.Errorf("first message = %+v", msgs[0])
+	}
+	if msgs[1].Photo == nil || (*msgs[1].Photo)[0].FileID != "AgADexisting" {
+		t.Errorf("second message = %+v", msgs[1])
+	}
+
+	// The uploaded file can be downloaded like the real ones
+	file := bot.GetFile((*msgs[0].Photo)[0].FileID)
+	if !file.Ok {
+		t.Fatalf("getFile failed: %+v", file)
+	}
+	r, err := bot.DownloadFilePathReader(file.Result.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := ioutil.ReadAll(r); string(data) != "meow" {
+		t.Errorf("downloaded %q, want meow", data)
+	}
+	if got := srv.CallsTo("sendMediaGroup")[0].Files["cat"].Name; got != "cat.jpg" {
+		t.Errorf("file name = %q, want cat.jpg", got)
+	}
+}
+
+func TestServerCopyMessage(t *testing.T) {
+	_, bot := newTestBot(t)
+	params := map[string]int{"chat_id": 42, "from_chat_id": 7, "message_id": 1}
+	id, err := tgbot.Call[struct {
+		MessageID int `json:"message_id"`
+	}](context.Background(), *bot, "copyMessage", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.MessageID == 0 {
+		t.Error("copyMessage didn't answer the new message ID")
+	}
+}
+
+func TestServerWebhook(t *testing.T) {
+	srv, bot := newTestBot(t)
+	if _, err := bot.SetWebhook("https://example.com/hook"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Webhook().URL; got != "https://example.com/hook" {
+		t.Errorf("webhook = %q", got)
+	}
+	if _, err := bot.GetUpdates(); err == nil {
+		t.Error("getUpdates worked with a webhook set")
+	}
+}
+
+func TestServerAllowedUpdatesOfTheHandlers(t *testing.T) {
+	srv, bot := newTestBot(t)
+	bot.CommandFn(`start`, func(bot tgbot.TgBot, msg tgbot.Message, args []string, kw map[string]string) *string {
+		return nil
+	})
+	srv.AddText(7, "/start")
+	if _, err := bot.GetUpdates(); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.CallsTo("getUpdates")[0].Params["allowed_updates"]; got != `["message"]` {
+		t.Errorf("allowed_updates = %s, want [\"message\"]", got)
+	}
+}
+
+func TestServerDefaultsOfTheQueries(t *testing.T) {
+	srv, bot := newTestBot(t)
+	bot.DefaultParseMode(tgbot.Html).DefaultOneTimeKeyboard(true)
+	caption := "<b>cat</b>"
+	var markup tgbot.ReplyMarkupInt = tgbot.ReplyKeyboardMarkup{Keyboard: tgbot.KeyboardLayout{{"yes"}}}
+	query := tgbot.SendPhotoIDQuery{ChatID: 42, Photo: "AgAD", Caption: &caption, SendOptions: tgbot.SendOptions{ReplyMarkup: &markup}}
+
+	for i, payload := range []interface{}{query, &query} {
+		name := fmt.Sprintf("%T", payload)
+		srv.Reset()
+		if res := bot.SendPhotoQuery(payload); !res.Ok {
+			t.Fatalf("%s: sendPhoto failed: %+v", name, res)
+		}
+		params := srv.CallsTo("sendPhoto")[0].Params
+		if params["parse_mode"] != "HTML" {
+			t.Errorf("%s: parse_mode = %q, want HTML", name, params["parse_mode"])
+		}
+		if !strings.Contains(params["reply_markup"], `"one_time_keyboard":true`) {
+			t.Errorf("%s: reply_markup = %s, want the one time keyboard", name, params["reply_markup"])
+		}
+		if i == 0 && query.ParseMode != nil {
+			t.Error("the query given by value was changed")
+		}
+	}
+}
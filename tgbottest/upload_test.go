@@ -0,0 +1,170 @@
+package tgbottest
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+type chatParams struct {
+	ChatID int `json:"chat_id"`
+}
+
+func tempFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// openCount returns how many times the process has the file open.
+func openCount(t *testing.T, file string) int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("/proc/self/fd is needed to see the open files")
+	}
+	count := 0
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && target == file {
+			count++
+		}
+	}
+	return count
+}
+
+// waitClosed fails if the file is still open after a second, the files are closed when the upload goroutine ends.
+func waitClosed(t *testing.T, file string) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if openCount(t, file) == 0 {
+			return
+		}
+	}
+	t.Errorf("%s is still open", file)
+}
+
+func TestUploadSeveralFiles(t *testing.T) {
+	srv, bot := newTestBot(t)
+	doc := tempFile(t, "notes.txt", "hello")
+	files := []tgbot.InputFile{
+		{Field: "document", File: doc},
+		{Field: "thumbnail", File: tgbot.ReaderSender{Read: strings.NewReader("world"), Name: "thumb.bin"}},
+		{Field: "photo", File: image.NewGray(image.Rect(0, 0, 2, 2))},
+	}
+	if _, err := tgbot.Call[tgbot.Message](context.Background(), *bot, "sendDocument", tgbot.WithFiles(chatParams{42}, files...)); err != nil {
+		t.Fatal(err)
+	}
+
+	call := srv.CallsTo("sendDocument")[0]
+	if call.Params["chat_id"] != "42" {
+		t.Errorf("params = %v", call.Params)
+	}
+	for field, want := range map[string]UploadedFile{
+		"document":  {"notes.txt", []byte("hello")},
+		"thumbnail": {"thumb.bin", []byte("world")},
+	} {
+		if got := call.Files[field]; got.Name != want.Name || string(got.Data) != string(want.Data) {
+			t.Errorf("%s = %s %q, want %s %q", field, got.Name, got.Data, want.Name, want.Data)
+		}
+	}
+	if got := call.Files["photo"]; got.Name != "image.jpeg" || len(got.Data) == 0 {
+		t.Errorf("photo = %s with %d bytes, want the JPEG", got.Name, len(got.Data))
+	}
+	waitClosed(t, doc)
+}
+
+func TestUploadProgress(t *testing.T) {
+	_, bot := newTestBot(t)
+	var mu sync.Mutex
+	reports := map[string][]tgbot.UploadProgress{}
+	bot.UploadProgress = func(p tgbot.UploadProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports[p.Field] = append(reports[p.Field], p)
+	}
+	files := []tgbot.InputFile{
+		{Field: "document", File: tempFile(t, "notes.txt", "hello")},
+		{Field: "thumbnail", File: tgbot.ReaderSender{Read: strings.NewReader("world!"), Name: "thumb.bin"}},
+	}
+	if _, err := tgbot.Call[tgbot.Message](context.Background(), *bot, "sendDocument", tgbot.WithFiles(chatParams{42}, files...)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for field, want := range map[string]tgbot.UploadProgress{
+		"document":  {Method: "sendDocument", Field: "document", Sent: 5, Total: 5},
+		"thumbnail": {Method: "sendDocument", Field: "thumbnail", Sent: 6, Total: -1}, // The size of a reader is unknown
+	} {
+		got := reports[field]
+		if len(got) == 0 || got[len(got)-1] != want {
+			t.Errorf("progress of %s = %+v, want it to end with %+v", field, got, want)
+		}
+	}
+}
+
+func TestUploadClosesTheFilesOnError(t *testing.T) {
+	srv, bot := newTestBot(t)
+	doc := tempFile(t, "notes.txt", "hello")
+	files := []tgbot.InputFile{{Field: "document", File: doc}, {Field: "thumbnail", File: 42}}
+	if _, err := tgbot.Call[tgbot.Message](context.Background(), *bot, "sendDocument", tgbot.WithFiles(chatParams{42}, files...)); err == nil {
+		t.Fatal("the upload of an int worked")
+	}
+	if len(srv.CallsTo("sendDocument")) != 0 {
+		t.Error("the request was sent")
+	}
+	waitClosed(t, doc)
+}
+
+// endlessReader never ends, it cancels the upload when it's first read.
+type endlessReader struct {
+	once   sync.Once
+	cancel func()
+}
+
+func (er *endlessReader) Read(p []byte) (int, error) {
+	er.once.Do(er.cancel)
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestUploadCancelled(t *testing.T) {
+	srv, bot := newTestBot(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	doc := tempFile(t, "notes.txt", "hello")
+	files := []tgbot.InputFile{
+		{Field: "document", File: doc},
+		{Field: "video", File: tgbot.ReaderSender{Read: &endlessReader{cancel: cancel}, Name: "endless.mp4"}},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tgbot.Call[tgbot.Message](ctx, *bot, "sendVideo", tgbot.WithFiles(chatParams{42}, files...))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cancelled upload didn't stop")
+	}
+	if len(srv.CallsTo("sendVideo")) != 0 {
+		t.Error("the cancelled upload got to the server")
+	}
+	waitClosed(t, doc)
+}
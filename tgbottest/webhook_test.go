@@ -0,0 +1,47 @@
+package tgbottest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rockneurotiko/go-tgbot"
+)
+
+// postUpdate sends the update to the webhook handler of the bot and returns the response.
+func postUpdate(t *testing.T, h http.Handler, update tgbot.Update) *httptest.ResponseRecorder {
+	body, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body)))
+	return w
+}
+
+func TestReplyInWebhookKeepsTheCallsWithResult(t *testing.T) {
+	srv, bot := newTestBot(t)
+	var file tgbot.ResultWithGetFile
+	bot.CommandFn(`file`, func(bot tgbot.TgBot, msg tgbot.Message, args []string, kw map[string]string) *string {
+		file = bot.GetFile("AgADnothing")
+		text := "done"
+		return &text
+	}).ReplyInWebhook()
+
+	w := postUpdate(t, bot.WebhookHandler(), TextMessage("/file").Update(1))
+	if len(srv.CallsTo("getFile")) != 1 {
+		t.Error("getFile was not called, it went in the webhook response")
+	}
+	if file.Ok || file.ErrorCode == nil {
+		t.Errorf("getFile answered %+v, want the error of the server", file)
+	}
+	if !strings.Contains(w.Body.String(), `"method":"sendMessage"`) {
+		t.Errorf("the reply is not in the response: %s", w.Body)
+	}
+	if len(srv.CallsTo("sendMessage")) != 0 {
+		t.Error("the reply was sent in a new request")
+	}
+}
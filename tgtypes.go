@@ -1,6 +1,9 @@
 package tgbot
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // User ...
 type User struct {
@@ -27,28 +30,40 @@ type UserGroup struct { // For Message type!
 
 // Message ...
 type Message struct {
-	ID                  int          `json:"message_id"`
-	From                User         `json:"from"`
-	Date                int          `json:"date"`
-	Chat                UserGroup    `json:"chat"`
-	ForwardFrom         *User        `json:"forward_from,omitempty"`
-	ForwardDate         *int         `json:"forward_date,omitempty"`
-	ReplyToMessage      *Message     `json:"reply_to_message,omitempty"`
-	Text                *string      `json:"text,omitempty"`
-	Audio               *Audio       `json:"audio,omitempty"`
-	Voice               *Voice       `json:"voice,omitempty"`
-	Document            *Document    `json:"document,omitempty"`
-	Photo               *[]PhotoSize `json:"photo,omitempty"`
-	Sticker             *Sticker     `json:"sticker,omitempty"`
-	Video               *Video       `json:"video,omitempty"`
-	Caption             *string      `json:"caption,omitempty"`
-	Location            *Location    `json:"location,omitempty"`
-	NewChatParticipant  *User        `json:"new_chat_participant,omitempty"`
-	LeftChatParticipant *User        `json:"left_chat_participant,omitempty"`
-	NewChatTitle        *string      `json:"new_chat_title,omitempty"`
-	NewChatPhoto        *string      `json:"new_chat_photo,omitempty"`
-	DeleteChatPhoto     *bool        `json:"delete_chat_photo,omitempty"`
-	GroupChatCreated    *bool        `json:"group_chat_created,omitempty"`
+	ID                  int              `json:"message_id"`
+	From                User             `json:"from"`
+	Date                int              `json:"date"`
+	Chat                UserGroup        `json:"chat"`
+	ForwardFrom         *User            `json:"forward_from,omitempty"`
+	ForwardDate         *int             `json:"forward_date,omitempty"`
+	ReplyToMessage      *Message         `json:"reply_to_message,omitempty"`
+	Text                *string          `json:"text,omitempty"`
+	Audio               *Audio           `json:"audio,omitempty"`
+	Voice               *Voice           `json:"voice,omitempty"`
+	Document            *Document        `json:"document,omitempty"`
+	Photo               *[]PhotoSize     `json:"photo,omitempty"`
+	Sticker             *Sticker         `json:"sticker,omitempty"`
+	Video               *Video           `json:"video,omitempty"`
+	Caption             *string          `json:"caption,omitempty"`
+	Location            *Location        `json:"location,omitempty"`
+	NewChatParticipant  *User            `json:"new_chat_participant,omitempty"`
+	LeftChatParticipant *User            `json:"left_chat_participant,omitempty"`
+	NewChatTitle        *string          `json:"new_chat_title,omitempty"`
+	NewChatPhoto        *string          `json:"new_chat_photo,omitempty"`
+	DeleteChatPhoto     *bool            `json:"delete_chat_photo,omitempty"`
+	GroupChatCreated    *bool            `json:"group_chat_created,omitempty"`
+	Entities            *[]MessageEntity `json:"entities,omitempty"`
+	CaptionEntities     *[]MessageEntity `json:"caption_entities,omitempty"`
+}
+
+// MessageEntity is a special part of the text (a command, a mention, an URL, bold text...), the offset and length are in UTF-16 code units.
+type MessageEntity struct {
+	Type     string  `json:"type"`
+	Offset   int     `json:"offset"`
+	Length   int     `json:"length"`
+	URL      *string `json:"url,omitempty"`
+	User     *User   `json:"user,omitempty"`
+	Language *string `json:"language,omitempty"`
 }
 
 // PhotoSize ...
@@ -201,11 +216,13 @@ type ParseModeT int
 const (
 	Markdown ParseModeT = 1 + iota
 	Html
+	MarkdownV2
 )
 
 var parsemode = [...]string{
 	"Markdown",
 	"HTML",
+	"MarkdownV2",
 }
 
 func (pa ParseModeT) String() string {
@@ -244,10 +261,66 @@ type ResultWithGetFile struct {
 	Result *File `json:"result,omitempty"`
 }
 
+// WebhookInfo ...
+type WebhookInfo struct {
+	URL                          string   `json:"url"`
+	HasCustomCertificate         bool     `json:"has_custom_certificate"`
+	PendingUpdateCount           int      `json:"pending_update_count"`
+	IPAddress                    *string  `json:"ip_address,omitempty"`
+	LastErrorDate                *int     `json:"last_error_date,omitempty"`
+	LastErrorMessage             *string  `json:"last_error_message,omitempty"`
+	LastSynchronizationErrorDate *int     `json:"last_synchronization_error_date,omitempty"`
+	MaxConnections               *int     `json:"max_connections,omitempty"`
+	AllowedUpdates               []string `json:"allowed_updates,omitempty"`
+}
+
+// ResultWithWebhookInfo ...
+type ResultWithWebhookInfo struct {
+	ResultBase
+	Result *WebhookInfo `json:"result,omitempty"`
+}
+
 // MessageWithUpdateID ...
 type MessageWithUpdateID struct {
-	Msg      Message `json:"message"`
-	UpdateID int     `json:"update_id"`
+	Msg           Message        `json:"message"`
+	UpdateID      int            `json:"update_id"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+	InlineQuery   *InlineQuery   `json:"inline_query,omitempty"`
+	reply         *webhookReply
+	queued        time.Time
+}
+
+// Update is the name that Telegram uses for MessageWithUpdateID.
+type Update = MessageWithUpdateID
+
+// Type returns the kind of the update, like in allowed_updates: "message", "callback_query" or "inline_query".
+func (u MessageWithUpdateID) Type() string {
+	switch {
+	case u.CallbackQuery != nil:
+		return "callback_query"
+	case u.InlineQuery != nil:
+		return "inline_query"
+	}
+	return "message"
+}
+
+// CallbackQuery ...
+type CallbackQuery struct {
+	ID              string   `json:"id"`
+	From            User     `json:"from"`
+	Message         *Message `json:"message,omitempty"`
+	InlineMessageID *string  `json:"inline_message_id,omitempty"`
+	ChatInstance    string   `json:"chat_instance"`
+	Data            *string  `json:"data,omitempty"`
+}
+
+// InlineQuery ...
+type InlineQuery struct {
+	ID       string    `json:"id"`
+	From     User      `json:"from"`
+	Location *Location `json:"location,omitempty"`
+	Query    string    `json:"query"`
+	Offset   string    `json:"offset"`
 }
 
 // ResultGetUpdates ...
@@ -270,14 +343,24 @@ type ResultSetWebhook struct {
 	ErrorCode   *int   `json:"error_code,omitempty"`
 }
 
+// SendOptions are the options shared by all the methods that send a message, the queries embed them.
+type SendOptions struct {
+	ReplyToMessageID         *int            `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup              *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	DisableNotification      *bool           `json:"disable_notification,omitempty"`
+	ProtectContent           *bool           `json:"protect_content,omitempty"`
+	MessageThreadID          *int            `json:"message_thread_id,omitempty"`
+	AllowSendingWithoutReply *bool           `json:"allow_sending_without_reply,omitempty"`
+}
+
 // QuerySendMessage ...
 type QuerySendMessage struct {
-	ChatID                int             `json:"chat_id"`
-	Text                  string          `json:"text"`
-	ParseMode             *string         `json:"parse_mode,omitempty"`
-	DisableWebPagePreview *bool           `json:"disable_web_page_preview,omitempty"`
-	ReplyToMessageID      *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup           *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID                int              `json:"chat_id"`
+	Text                  string           `json:"text"`
+	ParseMode             *string          `json:"parse_mode,omitempty"`
+	DisableWebPagePreview *bool            `json:"disable_web_page_preview,omitempty"`
+	Entities              *[]MessageEntity `json:"entities,omitempty"`
+	SendOptions
 }
 
 // ForwardMessageQuery ...
@@ -285,125 +368,121 @@ type ForwardMessageQuery struct {
 	ChatID     int `json:"chat_id"`
 	FromChatID int `json:"from_chat_id"`
 	MessageID  int `json:"message_id"`
+	SendOptions
 }
 
 // SendPhotoIDQuery ...
 type SendPhotoIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Photo            string          `json:"photo"`
-	Caption          *string         `json:"caption,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID          int              `json:"chat_id"`
+	Photo           string           `json:"photo"`
+	Caption         *string          `json:"caption,omitempty"`
+	ParseMode       *string          `json:"parse_mode,omitempty"`
+	CaptionEntities *[]MessageEntity `json:"caption_entities,omitempty"`
+	SendOptions
 }
 
 // SendPhotoPathQuery ...
 type SendPhotoPathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Photo            string          `json:"photo"`
-	Caption          *string         `json:"caption,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID          int              `json:"chat_id"`
+	Photo           string           `json:"photo"`
+	Caption         *string          `json:"caption,omitempty"`
+	ParseMode       *string          `json:"parse_mode,omitempty"`
+	CaptionEntities *[]MessageEntity `json:"caption_entities,omitempty"`
+	SendOptions
 }
 
 // SendVoiceIDQuery ...
 type SendVoiceIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Voice            string          `json:"voice"`
-	Duration         *int            `json:"duration,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID   int    `json:"chat_id"`
+	Voice    string `json:"voice"`
+	Duration *int   `json:"duration,omitempty"`
+	SendOptions
 }
 
 // SendVoicePathQuery ...
 type SendVoicePathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Voice            string          `json:"voice"`
-	Duration         *int            `json:"duration,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID   int    `json:"chat_id"`
+	Voice    string `json:"voice"`
+	Duration *int   `json:"duration,omitempty"`
+	SendOptions
 }
 
 // SendAudioIDQuery ...
 type SendAudioIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Audio            string          `json:"audio"`
-	Duration         *int            `json:"duration,omitempty"`
-	Performer        *string         `json:"performer,omitempty"`
-	Title            *string         `json:"title,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID    int     `json:"chat_id"`
+	Audio     string  `json:"audio"`
+	Duration  *int    `json:"duration,omitempty"`
+	Performer *string `json:"performer,omitempty"`
+	Title     *string `json:"title,omitempty"`
+	SendOptions
 }
 
 // SendAudioPathQuery ...
 type SendAudioPathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Audio            string          `json:"audio"`
-	Duration         *int            `json:"duration,omitempty"`
-	Performer        *string         `json:"performer,omitempty"`
-	Title            *string         `json:"title,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID    int     `json:"chat_id"`
+	Audio     string  `json:"audio"`
+	Duration  *int    `json:"duration,omitempty"`
+	Performer *string `json:"performer,omitempty"`
+	Title     *string `json:"title,omitempty"`
+	SendOptions
 }
 
 // SendDocumentIDQuery ...
 type SendDocumentIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Document         string          `json:"document"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID   int    `json:"chat_id"`
+	Document string `json:"document"`
+	SendOptions
 }
 
 // SendDocumentPathQuery ...
 type SendDocumentPathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Document         string          `json:"document"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID   int    `json:"chat_id"`
+	Document string `json:"document"`
+	SendOptions
 }
 
 // SendStickerIDQuery ...
 type SendStickerIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Sticker          string          `json:"sticker"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID  int    `json:"chat_id"`
+	Sticker string `json:"sticker"`
+	SendOptions
 }
 
 // SendStickerPathQuery ...
 type SendStickerPathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Sticker          string          `json:"sticker"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID  int    `json:"chat_id"`
+	Sticker string `json:"sticker"`
+	SendOptions
 }
 
 // SendVideoIDQuery ...
 type SendVideoIDQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Video            string          `json:"video"`
-	Duration         *int            `json:"duration,omitempty"`
-	Caption          *string         `json:"caption,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID          int              `json:"chat_id"`
+	Video           string           `json:"video"`
+	Duration        *int             `json:"duration,omitempty"`
+	Caption         *string          `json:"caption,omitempty"`
+	ParseMode       *string          `json:"parse_mode,omitempty"`
+	CaptionEntities *[]MessageEntity `json:"caption_entities,omitempty"`
+	SendOptions
 }
 
 // SendVideoPathQuery ...
 type SendVideoPathQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Video            string          `json:"video"`
-	Duration         *int            `json:"duration,omitempty"`
-	Caption          *string         `json:"caption,omitempty"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID          int              `json:"chat_id"`
+	Video           string           `json:"video"`
+	Duration        *int             `json:"duration,omitempty"`
+	Caption         *string          `json:"caption,omitempty"`
+	ParseMode       *string          `json:"parse_mode,omitempty"`
+	CaptionEntities *[]MessageEntity `json:"caption_entities,omitempty"`
+	SendOptions
 }
 
 // SendLocationQuery ...
 type SendLocationQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Latitude         float64         `json:"latitude"`
-	Longitude        float64         `json:"longitude"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID    int     `json:"chat_id"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	SendOptions
 }
 
 // SendChatActionQuery ...
@@ -419,9 +498,35 @@ type GetUserProfilePhotosQuery struct {
 	Limit  *int `json:"limit,omitempty"`
 }
 
+// GetUpdatesQuery ...
+type GetUpdatesQuery struct {
+	Offset         *int     `json:"offset,omitempty"`
+	Limit          *int     `json:"limit,omitempty"`
+	Timeout        *int     `json:"timeout,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// PollingOptions configure the getUpdates calls done by Start.
+type PollingOptions struct {
+	Limit          int      // Max updates per call, 0 uses the Telegram default (100)
+	Timeout        *int     // Long-polling timeout in seconds, nil uses the default (60) and 0 does short polling
+	AllowedUpdates []string // nil derives them from the registered handlers
+}
+
 // SetWebhookQuery ...
 type SetWebhookQuery struct {
-	URL *string `json:"url,omitempty"`
+	URL                *string     `json:"url,omitempty"`
+	Certificate        interface{} `json:"-"` // File path or ReaderSender with the public key certificate
+	IPAddress          *string     `json:"ip_address,omitempty"`
+	MaxConnections     *int        `json:"max_connections,omitempty"`
+	AllowedUpdates     []string    `json:"allowed_updates"`
+	DropPendingUpdates *bool       `json:"drop_pending_updates,omitempty"`
+	SecretToken        *string     `json:"secret_token,omitempty"`
+}
+
+// DeleteWebhookQuery ...
+type DeleteWebhookQuery struct {
+	DropPendingUpdates *bool `json:"drop_pending_updates,omitempty"`
 }
 
 // SetWebhookCertQuery ...
@@ -432,10 +537,9 @@ type SetWebhookCertQuery struct {
 
 // GenericSendQuery ...
 type GenericSendQuery struct {
-	ChatID           int             `json:"chat_id"`
-	Data             interface{}     `json:"data"`
-	ReplyToMessageID *int            `json:"reply_to_message_id,omitempty"`
-	ReplyMarkup      *ReplyMarkupInt `json:"reply_markup,omitempty"`
+	ChatID int         `json:"chat_id"`
+	Data   interface{} `json:"data"`
+	SendOptions
 }
 
 // String conversions
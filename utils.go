@@ -2,23 +2,13 @@ package tgbot
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"path/filepath"
-	"reflect"
 	"regexp"
-	"sort"
 	"strings"
-
-	"github.com/fatih/camelcase"
-	"github.com/go-martini/martini"
-	"github.com/martini-contrib/binding"
-	"github.com/martini-contrib/gorelic"
-	"github.com/oleiade/reflections"
-	"github.com/rockneurotiko/gorequest"
 )
 
 func convertToCommand(reg string) string {
@@ -62,96 +52,30 @@ func looksLikePath(p string) bool {
 	return false
 }
 
-func isZeroOfUnderlyingType(x interface{}) bool {
-	return x == reflect.Zero(reflect.TypeOf(x)).Interface()
-}
-
-func isInList(v string, l []string) bool {
-	sort.Strings(l)
-	i := sort.SearchStrings(l, v)
-	return i < len(l) && l[i] == v
-}
-
-func convertInterfaceMap(p interface{}, except []string) map[string]string {
-	nint := map[string]string{}
-	var structItems map[string]interface{}
-
-	structItems, _ = reflections.Items(p)
-	for v, v2 := range structItems {
-		if isZeroOfUnderlyingType(v2) || isInList(v, except) {
-			continue
-		}
-		v = strings.ToLower(strings.Join(camelcase.Split(v), "_"))
-		switch val := v2.(type) {
-		case interface{}:
-			sv, _ := json.Marshal(val)
-			nint[v] = string(sv)
-		default:
-			nint[v] = fmt.Sprintf("%+v", v2)
-		}
-	}
-	return nint
-}
-
+// StartServerMultiplesBotsHostPort starts a server that receives the updates of all the bots, setting their webhooks if uri is not empty.
+// The bots whose webhook can't be set are skipped, newrelic is ignored. Use a BotRegistry if you want to mount it in your own server or add and remove bots.
 func StartServerMultiplesBotsHostPort(uri string, pathl string, host string, port string, newrelic *RelicConfig, bots ...*TgBot) {
-	var puri *url.URL
 	if uri != "" {
-		tmpuri, err := url.Parse(uri)
-		if err != nil {
-			fmt.Printf("Bad URL %s", uri)
+		if _, err := url.Parse(uri); err != nil {
+			defaultLogger.Error("Bad URL", "url", uri)
 			return
 		}
-		puri = tmpuri
 	}
 
-	botsmap := make(map[string]*TgBot)
+	registry := NewBotRegistry(pathl)
+	registry.URL = uri
 	for _, bot := range bots {
-		tokendiv := strings.Split(bot.Token, ":")
-		if len(tokendiv) != 2 {
-			return
-		}
-
-		tokenpath := fmt.Sprintf("%s%s", tokendiv[0], tokendiv[1])
-		botpathl := path.Join(pathl, tokenpath)
-
-		nuri, _ := puri.Parse(botpathl)
-		remoteuri := nuri.String()
-		res, error := bot.SetWebhook(remoteuri)
-
-		if error != nil {
-			ec := res.ErrorCode
-			fmt.Printf("Error setting the webhook: \nError code: %d\nDescription: %s\n", &ec, res.Description)
-			continue
-		}
-		if bot.MainListener == nil {
-			bot.StartMainListener()
+		if err := registry.Register(bot); err != nil {
+			bot.log().Error("Error registering the bot", "username", bot.Username, "error", err)
 		}
-		botsmap[tokenpath] = bot
 	}
 
-	pathtolisten := path.Join(pathl, "(?P<token>[a-zA-Z0-9-_]+)")
-
-	m := martini.Classic()
-	m.Post(pathtolisten, binding.Json(MessageWithUpdateID{}), func(params martini.Params, msg MessageWithUpdateID) {
-		bot, ok := botsmap[params["token"]]
-
-		if ok && msg.UpdateID > 0 && msg.Msg.ID > 0 {
-			bot.MainListener <- msg
-		} else {
-			fmt.Println("Someone tried with: ", params["token"], msg)
-		}
-	})
-
 	if newrelic != nil {
-		gorelic.InitNewrelicAgent(newrelic.Token, newrelic.Name, false)
-		m.Use(gorelic.Handler)
+		defaultLogger.Warn("New Relic is not supported anymore, the RelicConfig is ignored")
 	}
 
-	if host == "" || port == "" {
-		m.Run()
-	} else {
-		m.RunOnAddr(host + ":" + port)
-	}
+	err := http.ListenAndServe(legacyAddr(host, port), registry)
+	defaultLogger.Error("The webhook server stopped", "error", err)
 }
 
 // StartServerMultiplesBots ...
@@ -160,48 +84,47 @@ func StartServerMultiplesBots(uri string, pathl string, newrelic *RelicConfig, b
 }
 
 func splitResultInMessageError(ressm ResultWithMessage) (res Message, err error) {
-	if ressm.Ok && ressm.Result != nil {
-		res = *ressm.Result
+	if ressm.Ok {
+		// The result is nil when the call was sent inside the webhook response
+		if ressm.Result != nil {
+			res = *ressm.Result
+		}
 		err = nil
 	} else {
 		res = Message{}
-		err = fmt.Errorf("Error in petition.\nError code: %d\nDescription: %s", *ressm.ErrorCode, *ressm.Description)
+		errc, desc := 0, ""
+		if ressm.ErrorCode != nil {
+			errc = *ressm.ErrorCode
+		}
+		if ressm.Description != nil {
+			desc = *ressm.Description
+		}
+		err = fmt.Errorf("Error in petition.\nError code: %d\nDescription: %s", errc, desc)
 	}
 	return
 }
 
-func postPetition(url string, payload interface{}, ctype *string) (string, error) {
-	request := gorequest.New().
-		DisableKeepAlives(true).
-		CloseRequest(true).
-		Post(url).
-		Send(payload)
-	request.TargetType = "form"
-
-	if ctype != nil {
-		request.Set("Content-Type", *ctype)
-	}
-
-	_, body, err := request.End()
-
+// formValues converts the payload in a form like Telegram expects it, the values that are not strings are sent in JSON.
+func formValues(payload interface{}) (url.Values, error) {
+	raw, err := json.Marshal(payload)
 	if err != nil {
-		return "", errors.New("Some error happened")
+		return nil, err
 	}
-	return body, nil
-}
-
-func getPetition(url string, queries []string) (string, error) {
-	req := gorequest.New().
-		DisableKeepAlives(true).
-		CloseRequest(true).
-		Get(url)
-
-	for _, q := range queries {
-		req.Query(q)
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
 	}
-	_, body, errq := req.End()
-	if errq != nil {
-		return "", errors.New("There were some error trying to do the petition")
+	form := url.Values{}
+	for k, v := range fields {
+		var s string
+		switch {
+		case string(v) == "null":
+			continue
+		case json.Unmarshal(v, &s) == nil:
+			form.Set(k, s)
+		default:
+			form.Set(k, string(v))
+		}
 	}
-	return body, nil
+	return form, nil
 }